@@ -0,0 +1,142 @@
+package restful
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	ErrMissingParam      = errors.New("the query references a parameter that was not provided")
+	ErrUnterminatedQuote = errors.New("the query contains an unterminated quote")
+)
+
+// PreparePositional works like Prepare, but renders the positional placeholders of the configured
+// dialect and returns the arguments in order, ready to be passed to database/sql.
+func PreparePositional(cfg Config, req Request) (string, []interface{}, error) {
+	query, args, err := Prepare(cfg, req)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return Positional(cfg.dialect(), query, args)
+}
+
+// CountPositional works like Count, but returns positional placeholders and arguments.
+func CountPositional(cfg Config, req Request) (string, []interface{}, error) {
+	query, args, err := Count(cfg, req)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return Positional(cfg.dialect(), query, args)
+}
+
+// PrepareCountPositional works like PrepareCount, but returns positional placeholders and arguments.
+func PrepareCountPositional(cfg Config, req Request, searchTarget string) (string, []interface{}, error) {
+	query, args, err := PrepareCount(cfg, req, searchTarget)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return Positional(cfg.dialect(), query, args)
+}
+
+// Positional replaces all named parameters (:name) of the query with the placeholders of the
+// given dialect. Parameters that are used multiple times are added once per occurrence.
+// Quoted strings and identifiers as well as PostgreSQL casts (::type) are left untouched.
+func Positional(d Dialect, query string, args map[string]interface{}) (string, []interface{}, error) {
+
+	if d == nil {
+		d = MySQL
+	}
+
+	// MySQL also escapes quotes of string literals with a backslash
+	backslash := d.Name() == MySQL.Name()
+
+	var out strings.Builder
+	list := make([]interface{}, 0, len(args))
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			// Copy quoted parts as they are
+			end := skipQuoted(query, i, backslash && c != '`')
+			if end < 0 {
+				return "", nil, fmt.Errorf("%w at %d", ErrUnterminatedQuote, i)
+			}
+
+			out.WriteString(query[i:end])
+			i = end - 1
+
+		case c == ':' && i+1 < len(query) && query[i+1] == ':':
+			// Casts
+			out.WriteString("::")
+			i++
+
+		case c == ':' && paramEnd(query, i+1) > i+1:
+			end := paramEnd(query, i+1)
+			name := query[i+1 : end]
+			value, ok := args[name]
+			if !ok {
+				return "", nil, fmt.Errorf("%w: %s", ErrMissingParam, name)
+			}
+
+			list = append(list, value)
+			out.WriteString(d.Placeholder(len(list)))
+			i = end - 1
+
+		default:
+			out.WriteByte(c)
+		}
+	}
+
+	return out.String(), list, nil
+}
+
+// Returns the index after the quoted part that starts at the given position, or -1 if the
+// quote is never closed. Doubled quotes are treated as escaped quotes, a backslash optionally
+// escapes the next character.
+func skipQuoted(s string, start int, backslash bool) int {
+	quote := s[start]
+
+	for i := start + 1; i < len(s); i++ {
+		if backslash && s[i] == '\\' {
+			i++
+			continue
+		}
+
+		if s[i] != quote {
+			continue
+		}
+
+		if i+1 < len(s) && s[i+1] == quote {
+			i++
+			continue
+		}
+
+		return i + 1
+	}
+
+	return -1
+}
+
+// Returns the index after the parameter name that starts at the given position. Names may
+// contain any unicode letter, like the field names of the filter.
+func paramEnd(s string, start int) int {
+	end := start
+	for end < len(s) {
+		r, size := utf8.DecodeRuneInString(s[end:])
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+
+		end += size
+	}
+
+	return end
+}
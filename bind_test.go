@@ -0,0 +1,100 @@
+package restful_test
+
+import (
+	"errors"
+	"github.com/joernlenoch/go-restful"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPositional(t *testing.T) {
+	t.Parallel()
+
+	query, args, err := restful.Positional(restful.PostgreSQL,
+		"SELECT a::text, ':skip' FROM t WHERE a = :a AND b = :b OR c = :a",
+		map[string]interface{}{"a": 1, "b": "x"},
+	)
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT a::text, ':skip' FROM t WHERE a = $1 AND b = $2 OR c = $3", query)
+	assert.Equal(t, []interface{}{1, "x", 1}, args)
+
+	_, _, err = restful.Positional(restful.MySQL, "SELECT a FROM t WHERE a = :missing", nil)
+	assert.True(t, errors.Is(err, restful.ErrMissingParam))
+
+	// MySQL escapes quotes with a backslash as well
+	query, args, err = restful.Positional(restful.MySQL,
+		`SELECT a FROM t WHERE n != 'it\'s :x' AND y = :y AND z = "\\"`,
+		map[string]interface{}{"y": 2},
+	)
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, `SELECT a FROM t WHERE n != 'it\'s :x' AND y = ? AND z = "\\"`, query)
+	assert.Equal(t, []interface{}{2}, args)
+
+	_, _, err = restful.Positional(restful.PostgreSQL, `SELECT a FROM t WHERE n != 'it\'s' AND y = :y`, map[string]interface{}{"y": 2})
+	assert.True(t, errors.Is(err, restful.ErrUnterminatedQuote))
+}
+
+func TestPreparePositional(t *testing.T) {
+	t.Parallel()
+
+	query, args, err := restful.PreparePositional(restful.Config{
+		Fields: restful.Fields{
+			restful.Field("name").Searchable(),
+			restful.Field("age"),
+		},
+		Table:            "user",
+		Where:            "tenant = :tenant",
+		AdditionalParams: restful.Params{"tenant": 7},
+	}, restful.Request{
+		Filter: "age=4",
+		Search: "john",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name, age FROM user WHERE (tenant = ?) AND age = ? AND name LIKE ? ESCAPE '!' LIMIT 50", query)
+	assert.Equal(t, []interface{}{7, "4", "%john%"}, args)
+
+	// Field and param names may contain any letter
+	query, args, err = restful.PreparePositional(restful.Config{
+		Fields: restful.Fields{
+			restful.Field("größe"),
+		},
+		Table:            "shoe",
+		Where:            "maß = :maß",
+		AdditionalParams: restful.Params{"maß": 1},
+		Dialect:          restful.PostgreSQL,
+	}, restful.Request{
+		Filter: "größe=42",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, `SELECT größe FROM shoe WHERE (maß = $1) AND größe = $2 LIMIT 50`, query)
+	assert.Equal(t, []interface{}{1, "42"}, args)
+}
+
+func TestCountPositional(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("name"),
+			restful.Field("age"),
+		},
+		Table:   "user",
+		Dialect: restful.PostgreSQL,
+	}
+
+	query, args, err := restful.CountPositional(cfg, restful.Request{Filter: "age=4,name=x"})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT COUNT(*) FROM (SELECT name, age FROM user WHERE age = $1 AND name = $2) t", query)
+	assert.Equal(t, []interface{}{"4", "x"}, args)
+
+	query, args, err = restful.PrepareCountPositional(cfg, restful.Request{Filter: "age=4"}, "*")
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT COUNT(*) FROM user WHERE age = $1", query)
+	assert.Equal(t, []interface{}{"4"}, args)
+}
@@ -0,0 +1,185 @@
+package restful
+
+import (
+	"strings"
+)
+
+type (
+
+	// Builder composes a Config step by step. The resulting config is processed exactly like
+	// a hand written one, therefore all requests are whitelisted the same way.
+	//
+	//	restful.Select(restful.Field("name")).
+	//		From("user_company").Join("company").Using("company_id").
+	//		Where("deleted = 0").
+	//		Prepare(req)
+	Builder struct {
+		cfg   Config
+		where whereGroup
+		group []string
+	}
+
+	// A chain of conditions joined by the same operator
+	whereGroup struct {
+		op    string
+		terms []string
+	}
+)
+
+// Select starts a new builder with the given fields.
+func Select(fields ...field) *Builder {
+	b := &Builder{}
+	return b.Fields(fields...)
+}
+
+// Fields adds more fields to the selection.
+func (b *Builder) Fields(fields ...field) *Builder {
+	b.cfg.Fields = append(b.cfg.Fields, fields...)
+	return b
+}
+
+func (b *Builder) Distinct() *Builder {
+	b.cfg.Distinct = true
+	return b
+}
+
+func (b *Builder) CalcRows() *Builder {
+	b.cfg.CalcRows = true
+	return b
+}
+
+func (b *Builder) From(table string) *Builder {
+	b.cfg.Table = table
+	return b
+}
+
+// Join adds an inner join. See As, On and Using to complete the join.
+func (b *Builder) Join(table string) *Builder {
+	b.cfg.Joins = append(b.cfg.Joins, InnerJoin(table, ""))
+	return b
+}
+
+func (b *Builder) LeftJoin(table string) *Builder {
+	b.cfg.Joins = append(b.cfg.Joins, LeftJoin(table, ""))
+	return b
+}
+
+func (b *Builder) RightJoin(table string) *Builder {
+	b.cfg.Joins = append(b.cfg.Joins, RightJoin(table, ""))
+	return b
+}
+
+// As sets the alias of the last join, that is used to address the columns of the table.
+func (b *Builder) As(alias string) *Builder {
+	if n := len(b.cfg.Joins); n > 0 {
+		b.cfg.Joins[n-1].Alias = alias
+	}
+
+	return b
+}
+
+// On sets the join condition of the last join.
+func (b *Builder) On(cond string) *Builder {
+	if n := len(b.cfg.Joins); n > 0 {
+		b.cfg.Joins[n-1] = b.cfg.Joins[n-1].On(cond)
+	}
+	return b
+}
+
+// Using sets the shared columns of the last join.
+func (b *Builder) Using(columns ...string) *Builder {
+	if n := len(b.cfg.Joins); n > 0 {
+		b.cfg.Joins[n-1] = b.cfg.Joins[n-1].Using(columns...)
+	}
+	return b
+}
+
+// Where adds a fixed condition. It is joined with AND to all previous conditions.
+func (b *Builder) Where(cond string) *Builder {
+	return b.And(cond)
+}
+
+func (b *Builder) And(cond string) *Builder {
+	b.where.add("AND", cond)
+	return b
+}
+
+// Or joins the condition with OR to all previous conditions, e.g.
+// Where("a").And("b").Or("c") results in "((a) AND (b)) OR (c)".
+func (b *Builder) Or(cond string) *Builder {
+	b.where.add("OR", cond)
+	return b
+}
+
+func (b *Builder) GroupBy(columns ...string) *Builder {
+	b.group = append(b.group, columns...)
+	return b
+}
+
+// Params adds additional named params that are used within the conditions.
+func (b *Builder) Params(p Params) *Builder {
+	if b.cfg.AdditionalParams == nil {
+		b.cfg.AdditionalParams = Params{}
+	}
+
+	for k, v := range p {
+		b.cfg.AdditionalParams[k] = v
+	}
+
+	return b
+}
+
+func (b *Builder) Dialect(d Dialect) *Builder {
+	b.cfg.Dialect = d
+	return b
+}
+
+// Config returns the composed configuration.
+func (b *Builder) Config() Config {
+	cfg := b.cfg
+	cfg.Joins = append(Joins(nil), b.cfg.Joins...)
+	cfg.Where = b.where.String()
+	cfg.GroupBy = strings.Join(b.group, ", ")
+
+	return cfg
+}
+
+func (b *Builder) Prepare(req Request) (string, map[string]interface{}, error) {
+	return Prepare(b.Config(), req)
+}
+
+func (b *Builder) Count(req Request) (string, map[string]interface{}, error) {
+	return Count(b.Config(), req)
+}
+
+func (b *Builder) PrepareCount(req Request, searchTarget string) (string, map[string]interface{}, error) {
+	return PrepareCount(b.Config(), req, searchTarget)
+}
+
+// Adds a condition. Whenever the operator changes, the previous conditions are grouped to keep
+// the evaluation from left to right.
+func (w *whereGroup) add(op string, cond string) {
+	if len(cond) == 0 {
+		return
+	}
+
+	if len(w.terms) > 1 && w.op != op {
+		w.terms = []string{w.String()}
+	}
+
+	w.op = op
+	w.terms = append(w.terms, cond)
+}
+
+func (w whereGroup) String() string {
+	if len(w.terms) < 2 {
+		return strings.Join(w.terms, "")
+	}
+
+	parts := make([]string, len(w.terms))
+	for i, t := range w.terms {
+		parts[i] = "(" + t + ")"
+	}
+
+	return strings.Join(parts, " "+w.op+" ")
+}
@@ -0,0 +1,62 @@
+package restful_test
+
+import (
+	"github.com/joernlenoch/go-restful"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	t.Parallel()
+
+	query, args, err := restful.Select().Distinct().
+		Fields(
+			restful.Field("company_id").Required(),
+			restful.Field("name"),
+			restful.Field("roles").QueryBy(`CONCAT("[", GROUP_CONCAT(JSON_QUOTE(role)),"]")`),
+		).
+		Where("user_id = :user").And("active = 1").Or("admin = 1").
+		Params(restful.Params{"user": 5}).
+		From("user_company").Join("company").Using("company_id").
+		GroupBy("company_id").
+		Prepare(restful.Request{
+			Fields: "name",
+			Filter: "name=acme",
+		})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT DISTINCT company_id, name FROM user_company INNER JOIN company USING (company_id) "+
+		"WHERE (((user_id = :user) AND (active = 1)) OR (admin = 1)) AND name = :__restful_filter0 GROUP BY company_id LIMIT 50", query)
+	assert.Equal(t, map[string]interface{}{"user": 5, "__restful_filter0": "acme"}, args)
+}
+
+func TestBuilder_Whitelist(t *testing.T) {
+	t.Parallel()
+
+	b := restful.Select(restful.Field("name")).From("user")
+
+	_, _, err := b.Prepare(restful.Request{Filter: "password=x"})
+	assert.Error(t, err, "must only allow configured fields")
+
+	_, _, err = b.Prepare(restful.Request{Order: "password"})
+	assert.Error(t, err, "must only allow configured fields")
+}
+
+func TestBuilder_Joins(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Select(restful.Field("name").QueryBy("c.name")).
+		From("user u").
+		LeftJoin("company").As("c").On("c.id = u.company_id").
+		RightJoin("team").As("t").Using("team_id", "company_id").
+		Where("u.active = 1").
+		Config()
+
+	assert.Equal(t, "user u", cfg.Table)
+	assert.Equal(t, "LEFT JOIN company c ON c.id = u.company_id RIGHT JOIN team t USING (team_id, company_id)", cfg.Joins.String())
+	assert.Equal(t, "u.active = 1", cfg.Where)
+
+	query, _, err := restful.Select(restful.Field("name")).From("user").Count(restful.Request{})
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT COUNT(*) FROM (SELECT name FROM user) t", query)
+}
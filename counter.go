@@ -1,13 +1,23 @@
 package restful
 
 import (
+	"context"
 	"fmt"
-	"strings"
 )
 
+// PrepareCount creates a query that counts the rows matching the request. The search target is
+// counted, e.g. "*" or a distinct column. When the config is grouped or the request filters by
+// an aggregate, the amount of groups is counted instead.
 func PrepareCount(cfg Config, req Request, searchTarget string) (query string, args map[string]interface{}, err error) {
+	return PrepareCountContext(context.Background(), cfg, req, searchTarget)
+}
+
+// PrepareCountContext works like PrepareCount, but only allows the fields that the principal
+// of the context can access.
+func PrepareCountContext(ctx context.Context, cfg Config, req Request, searchTarget string) (query string, args map[string]interface{}, err error) {
 
 	args = map[string]interface{}{}
+	cfg = cfg.restrict(PrincipalFrom(ctx)).named()
 
 	// Add the fixed (or default) fields
 	if len(cfg.Fields) == 0 {
@@ -15,15 +25,14 @@ func PrepareCount(cfg Config, req Request, searchTarget string) (query string, a
 		return
 	}
 
-	var filter string
-
-	if filter, err = prepareFilter(req.Filter, &args, cfg.Fields); err != nil {
-		return
+	filter, having, err := prepareFilter(req.Filter, &args, cfg)
+	if err != nil {
+		return "", nil, asQueryError("filter", req.Filter, err)
 	}
 
-	var search string
-	if search, err = prepareSearch(cfg.Fields, &args, req.Search); err != nil {
-		return
+	search, err := prepareSearch(cfg, &args, req.Search)
+	if err != nil {
+		return "", nil, asQueryError("search", req.Search, err)
 	}
 
 	// Merge the filter params and the custom ones
@@ -33,43 +42,29 @@ func PrepareCount(cfg Config, req Request, searchTarget string) (query string, a
 		}
 	}
 
-	// Build the query
-	query = "SELECT COUNT("
-
-	if cfg.Distinct && searchTarget != "*" {
-		query += "DISTINCT "
+	q := &Query{
+		From:    cfg.from(),
+		Where:   cfg.where(filter, search),
+		Args:    args,
+		Dialect: cfg.dialect(),
 	}
 
-	query = fmt.Sprintf("%s%s) FROM %s", query, searchTarget, cfg.Table)
-
-	//
-	// WHERE
-	//
+	// Grouped resources count the groups instead of the rows. Without a GROUP BY, a HAVING
+	// treats all rows as a single group, so there is either one row or none.
+	if len(cfg.GroupBy) > 0 || !having.IsEmpty() {
+		q.Select = []Column{{Name: "1"}}
+		q.GroupBy = cfg.GroupBy
+		q.Having = having
 
-	requirements := []string{}
-	if len(cfg.Where) > 0 {
-		requirements = append(requirements, cfg.Where)
+		return fmt.Sprintf("SELECT COUNT(*) FROM (%s) t", q.SQL()), args, nil
 	}
 
-	if len(filter) > 0 {
-		requirements = append(requirements, filter)
-	}
-
-	if len(search) > 0 {
-		requirements = append(requirements, search)
-	}
-
-	if len(requirements) > 0 {
-		query += " WHERE " + strings.Join(requirements, " AND ")
+	target := searchTarget
+	if cfg.Distinct && searchTarget != "*" {
+		target = "DISTINCT " + target
 	}
 
-	//
-	// GROUP
-	//
-
-	if len(cfg.GroupBy) > 0 {
-		query += fmt.Sprintf(" GROUP BY %s", cfg.GroupBy)
-	}
+	q.Select = []Column{{Name: fmt.Sprintf("COUNT(%s)", target)}}
 
-	return query, args, nil
+	return q.SQL(), args, nil
 }
@@ -41,7 +41,78 @@ func TestPrepareCount_FilterSearch(t *testing.T) {
 	}, "name")
 
 	assert.NoError(t, err, "must not throw errors")
-	assert.Equal(t, "SELECT COUNT(DISTINCT name) FROM user WHERE name LIKE :name0 AND (age LIKE :__restful_search OR roles LIKE :__restful_search)", query)
+	assert.Equal(t, "SELECT COUNT(DISTINCT name) FROM user WHERE name LIKE :__restful_filter0 ESCAPE '!' AND (age LIKE :__restful_search ESCAPE '!' OR (SELECT * FROM roles WHERE roles.name = name) LIKE :__restful_search ESCAPE '!')", query)
 	assert.Equal(t, 2, len(args), "should have 1 arguments")
-	assert.Equal(t, "%a%sd%", args["name0"], "should have transformed args")
+	assert.Equal(t, "%a%sd%", args["__restful_filter0"], "should have transformed args")
+}
+
+func TestPrepareCount_Grouped(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id").QueryBy("u.id"),
+			restful.Field("orders").QueryBy("COUNT(o.id)"),
+			restful.Field("total").QueryBy("order_total(u.id)").Aggregate(),
+		},
+		Table:   "user u",
+		Joins:   restful.Joins{restful.LeftJoin("orders", "o").On("o.user_id = u.id")},
+		GroupBy: "u.id",
+	}
+
+	query, args, err := restful.PrepareCount(cfg, restful.Request{
+		Filter: "orders>5,id>10,total>100",
+	}, "*")
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT COUNT(*) FROM (SELECT 1 FROM user u LEFT JOIN orders o ON o.user_id = u.id "+
+		"WHERE u.id > :__restful_filter1 GROUP BY u.id HAVING COUNT(o.id) > :__restful_filter0 AND order_total(u.id) > :__restful_filter2) t", query)
+	assert.Equal(t, 3, len(args), "should have 3 arguments")
+
+	query, _, err = restful.PrepareCount(cfg, restful.Request{}, "u.id")
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT COUNT(*) FROM (SELECT 1 FROM user u LEFT JOIN orders o ON o.user_id = u.id GROUP BY u.id) t", query)
+}
+
+func TestPrepareCount_HavingWithoutGroup(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("name"),
+			restful.Field("cnt").QueryBy("COUNT(x)"),
+		},
+		Table: "user",
+	}
+
+	query, args, err := restful.PrepareCount(cfg, restful.Request{Filter: "cnt>5"}, "*")
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT COUNT(*) FROM (SELECT 1 FROM user HAVING COUNT(x) > :__restful_filter0) t", query)
+	assert.Equal(t, map[string]interface{}{"__restful_filter0": "5"}, args)
+}
+
+func TestCount_Grouped(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id").QueryBy("u.id"),
+			restful.Field("orders").QueryBy("COUNT(o.id)"),
+			restful.Field("total").QueryBy("order_total(u.id)").Aggregate(),
+		},
+		Table:   "user u",
+		Joins:   restful.Joins{restful.LeftJoin("orders", "o").On("o.user_id = u.id")},
+		GroupBy: "u.id",
+	}
+
+	query, _, err := restful.Count(cfg, restful.Request{
+		Fields: "id",
+		Filter: "total>100",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT COUNT(*) FROM (SELECT u.id AS 'id' FROM user u LEFT JOIN orders o ON o.user_id = u.id "+
+		"GROUP BY u.id HAVING order_total(u.id) > :__restful_filter0) t", query)
 }
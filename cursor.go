@@ -0,0 +1,313 @@
+package restful
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Keyset pagination
+//
+// Instead of an offset, the client passes an opaque cursor that contains the order values of the
+// last (or first) row of the previous page. The query then seeks past these values, which stays
+// fast on large tables. The order is always completed by the unique Config.CursorKey, to get a
+// stable order. All fields of the order must be selected and must not be NULL.
+//
+// Rows of a previous cursor are returned in reverse order, use Request.IsPrevious to detect this.
+
+var (
+	ErrCursorInvalid      = errors.New("the cursor is invalid")
+	ErrCursorNotSupported = errors.New("the resource does not support cursors")
+	ErrCursorValue        = errors.New("the row does not contain a valid value for every ordered field")
+)
+
+type cursorPayload struct {
+	Previous bool          `json:"p,omitempty"`
+	Order    string        `json:"o"`
+	Values   []interface{} `json:"v"`
+}
+
+// NextCursor creates the cursor for the page after the given (last) row. The row must contain the
+// values of all ordered fields, keyed by their name.
+func NextCursor(cfg Config, req Request, last map[string]interface{}) (string, error) {
+	return NextCursorContext(context.Background(), cfg, req, last)
+}
+
+// NextCursorContext works like NextCursor, but only allows the fields that the principal of the
+// context can access. The cursor must be created for the same principal as the query.
+func NextCursorContext(ctx context.Context, cfg Config, req Request, last map[string]interface{}) (string, error) {
+	return encodeCursor(cfg.restrict(PrincipalFrom(ctx)), req, last, false)
+}
+
+// PrevCursor creates the cursor for the page before the given (first) row.
+func PrevCursor(cfg Config, req Request, first map[string]interface{}) (string, error) {
+	return PrevCursorContext(context.Background(), cfg, req, first)
+}
+
+// PrevCursorContext works like PrevCursor, but only allows the fields that the principal of the
+// context can access.
+func PrevCursorContext(ctx context.Context, cfg Config, req Request, first map[string]interface{}) (string, error) {
+	return encodeCursor(cfg.restrict(PrincipalFrom(ctx)), req, first, true)
+}
+
+// IsPrevious tells if the request pages backwards. The rows of such a request are returned in
+// reverse order and must be reversed before they are sent to the client.
+func (req Request) IsPrevious() bool {
+	payload, _, err := splitCursor(req.Cursor)
+	if err != nil {
+		return false
+	}
+
+	var p cursorPayload
+	return json.Unmarshal(payload, &p) == nil && p.Previous
+}
+
+// Converts the cursor into a seek condition. Returns the order that must be used for the query and
+// whether the condition belongs into HAVING.
+func prepareCursor(cfg Config, order orderBy, cursor string, args *map[string]interface{}) (orderBy, string, bool, error) {
+
+	order, err := cursorOrder(cfg, order)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	p, err := decodeCursor(cfg, cursor)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	// The cursor must have been created for the same order
+	if p.Order != order.signature() || len(p.Values) != len(order) {
+		return nil, "", false, ErrCursorInvalid
+	}
+
+	if p.Previous {
+		order = order.reverse()
+	}
+
+	keys := make([]string, len(order))
+	aggregate := false
+
+	for i, t := range order {
+		v, err := t.field.cursorValue(p.Values[i])
+		if err != nil {
+			return nil, "", false, err
+		}
+
+		keys[i] = fmt.Sprintf("__restful_cursor%d", i)
+		(*args)[keys[i]] = v
+
+		aggregate = aggregate || t.field.isAggregate()
+	}
+
+	// (a > :a) OR (a = :a AND b > :b) OR ...
+	alternatives := make([]string, len(order))
+
+	for i, t := range order {
+		parts := make([]string, 0, i+1)
+
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf("%s = :%s", order[j].field.sortExpr(), keys[j]))
+		}
+
+		cmp := ">"
+		if t.direction == DESC {
+			cmp = "<"
+		}
+
+		parts = append(parts, fmt.Sprintf("%s %s :%s", t.field.sortExpr(), cmp, keys[i]))
+		alternatives[i] = "(" + strings.Join(parts, " AND ") + ")"
+	}
+
+	return order, "(" + strings.Join(alternatives, " OR ") + ")", aggregate, nil
+}
+
+// Completes the order with the unique cursor key.
+func cursorOrder(cfg Config, order orderBy) (orderBy, error) {
+
+	key, ok := cfg.Fields.find(cfg.CursorKey)
+	if len(cfg.CursorKey) == 0 || !ok {
+		return nil, ErrCursorNotSupported
+	}
+
+	// The values of the key are readable within the cursor
+	if key.forbidden {
+		return nil, ErrFieldForbidden
+	}
+
+	for _, t := range order {
+		if t.field.Name == key.Name {
+			return order, nil
+		}
+	}
+
+	out := make(orderBy, len(order), len(order)+1)
+	copy(out, order)
+
+	return append(out, orderTerm{field: key, direction: ASC}), nil
+}
+
+func encodeCursor(cfg Config, req Request, row map[string]interface{}, previous bool) (string, error) {
+
+	// The order must be resolved like the one of the query, see build
+	cfg = cfg.named().withRelevance(req.Search)
+
+	order, err := prepareOrder(req.Order, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	if order, err = cursorOrder(cfg, order); err != nil {
+		return "", err
+	}
+
+	p := cursorPayload{
+		Previous: previous,
+		Order:    order.signature(),
+		Values:   make([]interface{}, len(order)),
+	}
+
+	for i, t := range order {
+		v, ok := row[t.field.Name]
+		if !ok || v == nil {
+			return "", ErrCursorValue
+		}
+
+		// Most drivers return text columns as bytes
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+
+		// Times are decoded with their full precision, so textual ones are parsed by the field
+		if s, ok := v.(string); ok && t.field.Type == TypeTime {
+			if v, err = t.field.parse(s); err != nil {
+				return "", ErrCursorValue
+			}
+		}
+
+		p.Values[i] = v
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(data)
+
+	if len(cfg.CursorSecret) > 0 {
+		token += "." + base64.RawURLEncoding.EncodeToString(signCursor(cfg.CursorSecret, data))
+	}
+
+	return token, nil
+}
+
+func decodeCursor(cfg Config, cursor string) (cursorPayload, error) {
+
+	var p cursorPayload
+
+	data, signature, err := splitCursor(cursor)
+	if err != nil {
+		return p, err
+	}
+
+	if len(cfg.CursorSecret) > 0 && !hmac.Equal(signature, signCursor(cfg.CursorSecret, data)) {
+		return p, ErrCursorInvalid
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&p); err != nil {
+		return p, ErrCursorInvalid
+	}
+
+	return p, nil
+}
+
+// Splits the cursor into the payload and the optional signature.
+func splitCursor(cursor string) ([]byte, []byte, error) {
+
+	parts := strings.Split(cursor, ".")
+	if len(parts) > 2 {
+		return nil, nil, ErrCursorInvalid
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, nil, ErrCursorInvalid
+	}
+
+	var signature []byte
+	if len(parts) == 2 {
+		if signature, err = base64.RawURLEncoding.DecodeString(parts[1]); err != nil {
+			return nil, nil, ErrCursorInvalid
+		}
+	}
+
+	return data, signature, nil
+}
+
+func signCursor(secret []byte, data []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// Converts a decoded cursor value back into the type of the field.
+func (f field) cursorValue(v interface{}) (interface{}, error) {
+
+	switch x := v.(type) {
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return i, nil
+		}
+
+		if n, err := x.Float64(); err == nil {
+			return n, nil
+		}
+
+	case bool:
+		return x, nil
+
+	case string:
+		// Times are always encoded with their full precision
+		if f.Type == TypeTime {
+			t, err := time.Parse(time.RFC3339Nano, x)
+			if err != nil {
+				return nil, ErrCursorInvalid
+			}
+
+			return t, nil
+		}
+
+		parsed, err := f.parse(x)
+		if err != nil {
+			return nil, ErrCursorInvalid
+		}
+
+		return parsed, nil
+	}
+
+	return nil, ErrCursorInvalid
+}
+
+// Returns a compact representation of the order, e.g. "-created,id"
+func (o orderBy) signature() string {
+	parts := make([]string, len(o))
+	for i, t := range o {
+		parts[i] = t.field.Name
+		if t.direction == DESC {
+			parts[i] = "-" + parts[i]
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
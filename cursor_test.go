@@ -0,0 +1,153 @@
+package restful_test
+
+import (
+	"errors"
+	"github.com/joernlenoch/go-restful"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCursor_Next(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id").Int().Required(),
+			restful.Field("created").Time("2006-01-02").Required().OrderBy(restful.DESC),
+			restful.Field("name"),
+		},
+		Table:        "user",
+		CursorKey:    "id",
+		CursorSecret: []byte("secret"),
+	}
+	req := restful.Request{Limit: 10, Offset: 30}
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	cursor, err := restful.NextCursor(cfg, req, map[string]interface{}{"id": int64(42), "created": created})
+	assert.NoError(t, err, "must not throw errors")
+
+	req.Cursor = cursor
+	query, args, err := restful.Prepare(cfg, req)
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.False(t, req.IsPrevious())
+	assert.Equal(t, "SELECT id, created, name FROM user WHERE ((created < :__restful_cursor0) OR "+
+		"(created = :__restful_cursor0 AND id > :__restful_cursor1)) ORDER BY created DESC, id ASC LIMIT 10", query)
+	assert.Equal(t, map[string]interface{}{"__restful_cursor0": created, "__restful_cursor1": int64(42)}, args)
+
+	// Textual values, e.g. of a DATE column, are parsed with the layout of the field
+	cursor, err = restful.NextCursor(cfg, restful.Request{}, map[string]interface{}{"id": []byte("42"), "created": []byte("2024-01-02")})
+	assert.NoError(t, err, "must not throw errors")
+
+	_, args, err = restful.Prepare(cfg, restful.Request{Cursor: cursor})
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, map[string]interface{}{"__restful_cursor0": time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), "__restful_cursor1": int64(42)}, args)
+
+	_, err = restful.NextCursor(cfg, restful.Request{}, map[string]interface{}{"id": 42, "created": "02.01.2024"})
+	assert.True(t, errors.Is(err, restful.ErrCursorValue))
+}
+
+func TestCursor_Previous(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id").Int().Required(),
+			restful.Field("created").Time("2006-01-02").Required().OrderBy(restful.DESC),
+			restful.Field("name"),
+		},
+		Table:        "user",
+		CursorKey:    "id",
+		CursorSecret: []byte("secret"),
+	}
+	req := restful.Request{Order: "name", Filter: "name~=a", Limit: 10}
+
+	cursor, err := restful.PrevCursor(cfg, req, map[string]interface{}{"id": 7, "name": []byte("anna")})
+	assert.NoError(t, err, "must not throw errors")
+
+	req.Cursor = cursor
+	query, args, err := restful.Prepare(cfg, req)
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.True(t, req.IsPrevious())
+	assert.Equal(t, "SELECT id, created, name FROM user WHERE name LIKE :__restful_filter0 ESCAPE '!' AND ((name < :__restful_cursor0) OR "+
+		"(name = :__restful_cursor0 AND id < :__restful_cursor1)) ORDER BY name DESC, id DESC LIMIT 10", query)
+	assert.Equal(t, "anna", args["__restful_cursor0"])
+	assert.Equal(t, int64(7), args["__restful_cursor1"])
+
+	// The count ignores the cursor
+	query, _, err = restful.Count(cfg, req)
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT COUNT(*) FROM (SELECT id, created, name FROM user WHERE name LIKE :__restful_filter0 ESCAPE '!') t", query)
+}
+
+func TestCursor_Relevance(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id").Int(),
+			restful.Field("title").Searchable(),
+		},
+		Table:      "post",
+		CursorKey:  "id",
+		SearchMode: restful.SearchNatural,
+		Relevance:  "score",
+	}
+	req := restful.Request{Fields: "id", Search: "go", Order: "-score"}
+
+	cursor, err := restful.NextCursor(cfg, req, map[string]interface{}{"id": 3, "score": 1.5})
+	assert.NoError(t, err, "must not throw errors")
+
+	req.Cursor = cursor
+	query, args, err := restful.Prepare(cfg, req)
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT id FROM post WHERE "+
+		"((MATCH (title) AGAINST (:__restful_search IN NATURAL LANGUAGE MODE) < :__restful_cursor0) OR "+
+		"(MATCH (title) AGAINST (:__restful_search IN NATURAL LANGUAGE MODE) = :__restful_cursor0 AND id > :__restful_cursor1)) "+
+		"AND MATCH (title) AGAINST (:__restful_search IN NATURAL LANGUAGE MODE) ORDER BY MATCH (title) AGAINST (:__restful_search IN NATURAL LANGUAGE MODE) DESC, id ASC LIMIT 50", query)
+	assert.Equal(t, 1.5, args["__restful_cursor0"])
+}
+
+func TestCursor_Invalid(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id").Int().Required(),
+			restful.Field("created").Time("2006-01-02").Required().OrderBy(restful.DESC),
+			restful.Field("name"),
+		},
+		Table:        "user",
+		CursorKey:    "id",
+		CursorSecret: []byte("secret"),
+	}
+	cursor, err := restful.NextCursor(cfg, restful.Request{}, map[string]interface{}{"id": 1, "created": time.Now()})
+	assert.NoError(t, err, "must not throw errors")
+
+	// Tampered signature
+	_, _, err = restful.Prepare(cfg, restful.Request{Cursor: cursor + "A"})
+	assert.True(t, errors.Is(err, restful.ErrCursorInvalid))
+
+	// Created for a different order
+	_, _, err = restful.Prepare(cfg, restful.Request{Cursor: cursor, Order: "name"})
+	assert.True(t, errors.Is(err, restful.ErrCursorInvalid))
+
+	// Different secret
+	other := cfg
+	other.CursorSecret = []byte("other")
+	_, _, err = restful.Prepare(other, restful.Request{Cursor: cursor})
+	assert.True(t, errors.Is(err, restful.ErrCursorInvalid))
+
+	_, _, err = restful.Prepare(cfg, restful.Request{Cursor: "not-a-cursor"})
+	assert.True(t, errors.Is(err, restful.ErrCursorInvalid))
+
+	_, err = restful.NextCursor(cfg, restful.Request{}, map[string]interface{}{"id": 1})
+	assert.True(t, errors.Is(err, restful.ErrCursorValue))
+
+	cfg.CursorKey = ""
+	_, _, err = restful.Prepare(cfg, restful.Request{Cursor: cursor})
+	assert.True(t, errors.Is(err, restful.ErrCursorNotSupported))
+}
@@ -0,0 +1,205 @@
+package restful
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	// MySQL is the default dialect, used whenever a config does not define one.
+	MySQL Dialect = mysqlDialect{}
+
+	PostgreSQL Dialect = postgresDialect{}
+	SQLite     Dialect = sqliteDialect{}
+	SQLServer  Dialect = sqlServerDialect{}
+)
+
+// Dialect encapsulates the parts of the generated SQL that differ between database systems.
+type Dialect interface {
+
+	// Name returns a human readable name of the dialect
+	Name() string
+
+	// Quote quotes an identifier, e.g. a table or column name
+	Quote(ident string) string
+
+	// Alias quotes the name used in an "expr AS name" clause
+	Alias(name string) string
+
+	// Paginate returns the pagination clause. Ordered tells if the query already contains
+	// an ORDER BY clause, as some systems cannot paginate without one.
+	Paginate(limit, offset uint, ordered bool) string
+
+	// FoundRows returns the select modifier to calculate the total amount of rows or an
+	// empty string if the system does not support it.
+	FoundRows() string
+
+	// Like returns the case insensitive pattern matching operator
+	Like() string
+
+	// Placeholder returns the positional placeholder for the n-th (starting at 1) argument
+	Placeholder(n int) string
+
+	// DistinctFrom returns a NULL-safe inequality comparison, that is also true if only one
+	// side is NULL.
+	DistinctFrom(left, right string) string
+}
+
+type (
+	mysqlDialect     struct{}
+	postgresDialect  struct{}
+	sqliteDialect    struct{}
+	sqlServerDialect struct{}
+)
+
+//
+// MySQL / MariaDB
+//
+
+func (mysqlDialect) Name() string { return "mysql" }
+
+func (mysqlDialect) Quote(ident string) string {
+	return "`" + strings.Replace(ident, "`", "``", -1) + "`"
+}
+
+func (mysqlDialect) Alias(name string) string {
+	return "'" + strings.Replace(name, "'", "''", -1) + "'"
+}
+
+// MySQL does not accept an OFFSET without a LIMIT, the largest BIGINT UNSIGNED disables the limit.
+func (mysqlDialect) Paginate(limit, offset uint, ordered bool) string {
+	if limit == 0 && offset > 0 {
+		return fmt.Sprintf("LIMIT 18446744073709551615 OFFSET %d", offset)
+	}
+
+	return limitOffset(limit, offset)
+}
+
+func (mysqlDialect) FoundRows() string { return "SQL_CALC_FOUND_ROWS" }
+
+func (mysqlDialect) Like() string { return "LIKE" }
+
+func (mysqlDialect) Placeholder(n int) string { return "?" }
+
+func (mysqlDialect) DistinctFrom(left, right string) string {
+	return fmt.Sprintf("NOT (%s <=> %s)", left, right)
+}
+
+//
+// PostgreSQL
+//
+
+func (postgresDialect) Name() string { return "postgres" }
+
+func (postgresDialect) Quote(ident string) string { return doubleQuote(ident) }
+
+func (postgresDialect) Alias(name string) string { return doubleQuote(name) }
+
+func (postgresDialect) Paginate(limit, offset uint, ordered bool) string {
+	return limitOffset(limit, offset)
+}
+
+func (postgresDialect) FoundRows() string { return "" }
+
+// PostgreSQL compares case sensitive with LIKE, unlike the other systems.
+func (postgresDialect) Like() string { return "ILIKE" }
+
+func (postgresDialect) Placeholder(n int) string { return fmt.Sprintf("$%d", n) }
+
+func (postgresDialect) DistinctFrom(left, right string) string {
+	return fmt.Sprintf("%s IS DISTINCT FROM %s", left, right)
+}
+
+//
+// SQLite
+//
+
+func (sqliteDialect) Name() string { return "sqlite" }
+
+func (sqliteDialect) Quote(ident string) string { return doubleQuote(ident) }
+
+func (sqliteDialect) Alias(name string) string { return doubleQuote(name) }
+
+// SQLite does not accept an OFFSET without a LIMIT, -1 disables the limit.
+func (sqliteDialect) Paginate(limit, offset uint, ordered bool) string {
+	if limit == 0 && offset > 0 {
+		return fmt.Sprintf("LIMIT -1 OFFSET %d", offset)
+	}
+
+	return limitOffset(limit, offset)
+}
+
+func (sqliteDialect) FoundRows() string { return "" }
+
+func (sqliteDialect) Like() string { return "LIKE" }
+
+func (sqliteDialect) Placeholder(n int) string { return "?" }
+
+// IS DISTINCT FROM requires SQLite 3.39, IS NOT is the older equivalent.
+func (sqliteDialect) DistinctFrom(left, right string) string {
+	return fmt.Sprintf("%s IS NOT %s", left, right)
+}
+
+//
+// Microsoft SQL Server
+//
+
+func (sqlServerDialect) Name() string { return "sqlserver" }
+
+func (sqlServerDialect) Quote(ident string) string {
+	return "[" + strings.Replace(ident, "]", "]]", -1) + "]"
+}
+
+func (d sqlServerDialect) Alias(name string) string { return d.Quote(name) }
+
+// SQL Server requires an ORDER BY for OFFSET ... FETCH, therefore a neutral one is added
+// when the query is not ordered.
+func (sqlServerDialect) Paginate(limit, offset uint, ordered bool) string {
+	if limit == 0 && offset == 0 {
+		return ""
+	}
+
+	out := fmt.Sprintf("OFFSET %d ROWS", offset)
+	if limit > 0 {
+		out += fmt.Sprintf(" FETCH NEXT %d ROWS ONLY", limit)
+	}
+
+	if !ordered {
+		out = "ORDER BY (SELECT NULL) " + out
+	}
+
+	return out
+}
+
+func (sqlServerDialect) FoundRows() string { return "" }
+
+func (sqlServerDialect) Like() string { return "LIKE" }
+
+func (sqlServerDialect) Placeholder(n int) string { return fmt.Sprintf("@p%d", n) }
+
+// IS DISTINCT FROM requires SQL Server 2022, older versions need the expanded comparison.
+func (sqlServerDialect) DistinctFrom(left, right string) string {
+	return fmt.Sprintf("(%[1]s <> %[2]s OR (%[1]s IS NULL AND %[2]s IS NOT NULL) OR (%[1]s IS NOT NULL AND %[2]s IS NULL))", left, right)
+}
+
+//
+// Helpers
+//
+
+func doubleQuote(s string) string {
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
+
+func limitOffset(limit, offset uint) string {
+	parts := make([]string, 0, 2)
+
+	if limit > 0 {
+		parts = append(parts, fmt.Sprintf("LIMIT %d", limit))
+	}
+
+	if offset > 0 {
+		parts = append(parts, fmt.Sprintf("OFFSET %d", offset))
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,147 @@
+package restful_test
+
+import (
+	"github.com/joernlenoch/go-restful"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDialect_Default(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("name").QueryBy("u.name").Searchable(),
+			restful.Field("age").OrderBy(restful.ASC),
+		},
+		Table:    "user u",
+		CalcRows: true,
+	}
+
+	query, _, err := restful.Prepare(cfg, restful.Request{
+		Filter: "name~=a",
+		Limit:  10,
+		Offset: 20,
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT SQL_CALC_FOUND_ROWS u.name AS 'name', age FROM user u WHERE u.name LIKE :__restful_filter0 ESCAPE '!' ORDER BY age ASC LIMIT 10 OFFSET 20", query)
+
+	// MySQL does not accept an offset without a limit
+	cfg.NoLimit = true
+
+	query, _, err = restful.Prepare(cfg, restful.Request{Offset: 20})
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT SQL_CALC_FOUND_ROWS u.name AS 'name', age FROM user u ORDER BY age ASC LIMIT 18446744073709551615 OFFSET 20", query)
+}
+
+func TestDialect_PostgreSQL(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("name").QueryBy("u.name").Searchable(),
+			restful.Field("age").OrderBy(restful.ASC),
+		},
+		Table:    "user u",
+		CalcRows: true,
+		Dialect:  restful.PostgreSQL,
+	}
+
+	query, _, err := restful.Prepare(cfg, restful.Request{
+		Filter: "name~=a",
+		Search: "b",
+		Limit:  10,
+		Offset: 20,
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, `SELECT u.name AS "name", age FROM user u WHERE u.name ILIKE :__restful_filter0 ESCAPE '!' AND u.name ILIKE :__restful_search ESCAPE '!' ORDER BY age ASC LIMIT 10 OFFSET 20`, query)
+}
+
+func TestDialect_SQLite(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("name").QueryBy("u.name").Searchable(),
+			restful.Field("age").OrderBy(restful.ASC),
+		},
+		Table:    "user u",
+		CalcRows: true,
+		Dialect:  restful.SQLite,
+		NoLimit:  true,
+	}
+
+	query, _, err := restful.Prepare(cfg, restful.Request{
+		Offset: 20,
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, `SELECT u.name AS "name", age FROM user u ORDER BY age ASC LIMIT -1 OFFSET 20`, query)
+}
+
+func TestDialect_SQLServer(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("name").QueryBy("u.name").Searchable(),
+			restful.Field("age").OrderBy(restful.ASC),
+		},
+		Table:    "user u",
+		CalcRows: true,
+		Dialect:  restful.SQLServer,
+	}
+
+	query, _, err := restful.Prepare(cfg, restful.Request{
+		Limit:  10,
+		Offset: 20,
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT u.name AS [name], age FROM user u ORDER BY age ASC OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY", query)
+
+	// Without any order, a neutral one must be added
+	query, _, err = restful.Prepare(restful.Config{
+		Fields:  restful.Fields{restful.Field("name")},
+		Table:   "user",
+		Dialect: restful.SQLServer,
+	}, restful.Request{
+		Limit: 10,
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name FROM user ORDER BY (SELECT NULL) OFFSET 0 ROWS FETCH NEXT 10 ROWS ONLY", query)
+}
+
+func TestDialect_Quote(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "`a``b`", restful.MySQL.Quote("a`b"))
+	assert.Equal(t, `"a""b"`, restful.PostgreSQL.Quote(`a"b`))
+	assert.Equal(t, `"a"`, restful.SQLite.Quote("a"))
+	assert.Equal(t, "[a]]b]", restful.SQLServer.Quote("a]b"))
+}
+
+func TestDialect_Count(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("name").QueryBy("u.name").Searchable(),
+			restful.Field("age").OrderBy(restful.ASC),
+		},
+		Table:    "user u",
+		CalcRows: true,
+		Dialect:  restful.SQLServer,
+	}
+
+	query, _, err := restful.Count(cfg, restful.Request{
+		Limit:  10,
+		Offset: 20,
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT COUNT(*) FROM (SELECT u.name AS [name], age FROM user u) t", query)
+}
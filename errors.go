@@ -12,10 +12,11 @@ import (
 var (
 	Development bool
 
-	MsgServerError  = "server-error"
-	MsgUnauthorized = "not-authenticated"
-	MsgForbidden    = "access-denied"
-	MsgNotFound     = "not-found"
+	MsgServerError    = "server-error"
+	MsgUnauthorized   = "not-authenticated"
+	MsgForbidden      = "access-denied"
+	MsgNotFound       = "not-found"
+	MsgInvalidRequest = "invalid-request"
 )
 
 // M is a simple string map for result parameters
@@ -87,15 +88,22 @@ func (r response) Error() string {
 	return fmt.Sprintf("%s", r.Message)
 }
 
+// The JSON body of a response
+type responseBody struct {
+	Tracking string      `json:"tracking,omitempty"`
+	Message  string      `json:"message"`
+	Reason   string      `json:"reason,omitempty"`
+	Stack    []string    `json:"stack,omitempty"`
+	Source   interface{} `json:"source,omitempty"`
+}
+
 func (r response) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.body())
+}
+
+func (r response) body() responseBody {
 
-	data := struct {
-		Tracking string      `json:"tracking,omitempty"`
-		Message  string      `json:"message"`
-		Reason   string      `json:"reason,omitempty"`
-		Stack    []string    `json:"stack,omitempty"`
-		Source   interface{} `json:"source,omitempty"`
-	}{
+	data := responseBody{
 		Tracking: r.Tracking,
 		Message:  r.Message,
 		Reason:   r.Reason,
@@ -111,7 +119,7 @@ func (r response) MarshalJSON() ([]byte, error) {
 		}
 	}
 
-	return json.Marshal(data)
+	return data
 }
 
 func (r response) GetCode() int {
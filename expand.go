@@ -0,0 +1,264 @@
+package restful
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrExpandInvalidStructure = errors.New("the expand string does not match the allowed structure")
+	ErrExpandNotAllowed       = errors.New("the relation cannot be expanded")
+	ErrExpandNoKeys           = errors.New("no keys given to load the relation for")
+)
+
+type (
+	Relations []relation
+
+	// Declaration of a related resource, that can be loaded along with the main query. The
+	// rows of the related config are matched by ForeignKey against the LocalKey of the main rows.
+	relation struct {
+		Name       string
+		Config     Config
+		LocalKey   string
+		ForeignKey string
+	}
+
+	// Expansion is the batched query of an expanded relation. It is prepared for the keys of
+	// all loaded main rows at once, which avoids a query per row.
+	Expansion struct {
+		Name string
+
+		// The field of the main rows that holds the keys
+		Key string
+
+		// The field of the related rows that refers to the keys
+		ForeignKey string
+
+		cfg Config
+		req Request
+
+		// The position of the field selection within the expand string
+		pos int
+	}
+)
+
+// Relation declares the related resource by its config and the fields (by name) that connect
+// both resources, e.g. Relation("company", companyConfig, "companyID", "id"). Both fields must
+// not be hidden, as they are selected to match the rows.
+func Relation(name string, cfg Config, localKey, foreignKey string) relation {
+	return relation{Name: name, Config: cfg, LocalKey: localKey, ForeignKey: foreignKey}
+}
+
+func (r Relations) find(name string) (relation, bool) {
+	for _, v := range r {
+		if v.Name == name {
+			return v, true
+		}
+	}
+
+	return relation{}, false
+}
+
+// PrepareExpand works like Prepare, but also returns the queries of the relations listed in
+// Request.Expand, e.g. "company(name,city),tags".
+func PrepareExpand(cfg Config, req Request) (query string, args map[string]interface{}, expansions []Expansion, err error) {
+	return PrepareExpandContext(context.Background(), cfg, req)
+}
+
+// PrepareExpandContext works like PrepareExpand, but only allows the fields (of all resources)
+// that the principal of the context can access.
+func PrepareExpandContext(ctx context.Context, cfg Config, req Request) (query string, args map[string]interface{}, expansions []Expansion, err error) {
+	p := PrincipalFrom(ctx)
+
+	if query, args, err = prepare(cfg.restrict(p), req, false); err != nil {
+		return
+	}
+
+	if expansions, err = prepareExpand(cfg, req.Expand); err != nil {
+		return "", nil, nil, asQueryError("expand", req.Expand, err)
+	}
+
+	// The selection is validated up front, before the caller loads the main rows
+	for i, e := range expansions {
+		expansions[i].cfg = e.cfg.restrict(p)
+
+		if _, err = selectFields(e.req.Fields, expansions[i].cfg.named().Fields); err != nil {
+			var qe *QueryError
+			if errors.As(err, &qe) {
+				return "", nil, nil, newQueryError("expand", qe.Token, e.pos+qe.Pos, qe.Err)
+			}
+
+			return "", nil, nil, asQueryError("expand", req.Expand, err)
+		}
+	}
+
+	return query, args, expansions, nil
+}
+
+// Prepare creates the query of the related rows for the given keys, usually the values of the
+// Key field of all loaded main rows. The foreign key is always selected to match the rows. Like
+// an IN filter, the amount of keys is limited by the MaxFilterValues of the related config.
+func (e Expansion) Prepare(keys ...interface{}) (query string, args map[string]interface{}, err error) {
+	if len(keys) == 0 {
+		return "", nil, ErrExpandNoKeys
+	}
+
+	cfg := e.cfg.named()
+
+	maxValues := cfg.MaxFilterValues
+	if maxValues <= 0 {
+		maxValues = FilterValuesDefault
+	}
+
+	if len(keys) > maxValues {
+		return "", nil, ErrFilterTooManyValues
+	}
+
+	f, ok := cfg.Fields.find(e.ForeignKey)
+	if !ok {
+		return "", nil, ErrExpandNotAllowed
+	}
+
+	if f.forbidden {
+		return "", nil, ErrFieldForbidden
+	}
+
+	req := e.req
+	if len(req.Fields) > 0 {
+		req.Fields = e.ForeignKey + "," + req.Fields
+	}
+
+	cfg.NoLimit = true
+
+	q, err := build(cfg, req, false)
+	if err != nil {
+		return "", nil, err
+	}
+
+	params := make([]string, len(keys))
+	for i, v := range keys {
+		key := fmt.Sprintf("__restful_expand%d", i)
+		params[i] = ":" + key
+		q.Args[key] = v
+	}
+
+	q.AddWhere(Raw(fmt.Sprintf("%s IN (%s)", f.filterExpr(), strings.Join(params, ", "))))
+
+	return q.SQL(), q.Args, nil
+}
+
+// Parses the expand string and validates the relations against the config.
+func prepareExpand(cfg Config, raw string) ([]Expansion, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	terms, err := splitExpand(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	expansions := make([]Expansion, 0, len(terms))
+	pos := 0
+
+	for _, term := range terms {
+		start := pos
+		pos += len([]rune(term)) + 1
+
+		match := expandRegex.FindStringSubmatch(term)
+		if match == nil {
+			return nil, ErrExpandInvalidStructure
+		}
+
+		rel, ok := cfg.Relations.find(match[1])
+		if !ok {
+			return nil, ErrExpandNotAllowed
+		}
+
+		// Both keys are selected to match the rows, which hidden fields never are
+		if f, ok := cfg.Fields.find(rel.LocalKey); !ok || f.IsHidden {
+			return nil, ErrExpandNotAllowed
+		}
+
+		if f, ok := rel.Config.Fields.find(rel.ForeignKey); !ok || f.IsHidden {
+			return nil, ErrExpandNotAllowed
+		}
+
+		// Expanding the same relation twice is pointless
+		for _, e := range expansions {
+			if e.Name == rel.Name {
+				return nil, ErrExpandInvalidStructure
+			}
+		}
+
+		expansions = append(expansions, Expansion{
+			Name:       rel.Name,
+			Key:        rel.LocalKey,
+			ForeignKey: rel.ForeignKey,
+			cfg:        rel.Config,
+			req:        Request{Fields: match[2]},
+			pos:        start + len([]rune(match[1])) + 1,
+		})
+	}
+
+	return expansions, nil
+}
+
+// Splits the expand string by the commas outside of the field selections.
+func splitExpand(raw string) ([]string, error) {
+	terms := make([]string, 0, 1)
+	depth := 0
+	start := 0
+
+	for i, r := range raw {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, ErrExpandInvalidStructure
+			}
+		case ',':
+			if depth == 0 {
+				terms = append(terms, raw[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	if depth != 0 {
+		return nil, ErrExpandInvalidStructure
+	}
+
+	return append(terms, raw[start:]), nil
+}
+
+// Adds the local keys of all expanded relations to the selection, as the related rows cannot
+// be matched without them.
+func selectExpandKeys(cfg Config, raw string, fields Fields) (Fields, error) {
+	expansions, err := prepareExpand(cfg, raw)
+	if err != nil {
+		return nil, err
+	}
+
+keysLoop:
+	for _, e := range expansions {
+		for _, f := range fields {
+			if f.Name == e.Key {
+				continue keysLoop
+			}
+		}
+
+		f, _ := cfg.Fields.find(e.Key)
+		if f.forbidden {
+			return nil, ErrFieldForbidden
+		}
+
+		fields = append(fields, f)
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,168 @@
+package restful_test
+
+import (
+	"context"
+	"errors"
+	"github.com/joernlenoch/go-restful"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPrepareExpand(t *testing.T) {
+	t.Parallel()
+
+	company := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id"),
+			restful.Field("name"),
+			restful.Field("city"),
+			restful.Field("revenue").RequireScopes("finance"),
+		},
+		Table: "company",
+		Where: "deleted = 0",
+	}
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id"),
+			restful.Field("name"),
+			restful.Field("companyID").QueryBy("company_id"),
+		},
+		Table: "user",
+		Relations: restful.Relations{
+			restful.Relation("company", company, "companyID", "id"),
+		},
+	}
+
+	query, _, expansions, err := restful.PrepareExpand(cfg, restful.Request{
+		Fields: "name",
+		Expand: "company(name,city)",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name, company_id AS 'companyID' FROM user LIMIT 50", query, "must select the key")
+	assert.Len(t, expansions, 1)
+
+	e := expansions[0]
+	assert.Equal(t, "company", e.Name)
+	assert.Equal(t, "companyID", e.Key)
+
+	query, args, err := e.Prepare(4, 8)
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT id, name, city FROM company WHERE (deleted = 0) AND id IN (:__restful_expand0, :__restful_expand1)", query)
+	assert.Equal(t, 4, args["__restful_expand0"])
+	assert.Equal(t, 8, args["__restful_expand1"])
+
+	_, _, err = e.Prepare()
+	assert.True(t, errors.Is(err, restful.ErrExpandNoKeys))
+
+	keys := make([]interface{}, restful.FilterValuesDefault+1)
+	for i := range keys {
+		keys[i] = i
+	}
+
+	_, _, err = e.Prepare(keys...)
+	assert.True(t, errors.Is(err, restful.ErrFilterTooManyValues))
+
+	_, _, err = e.Prepare(keys[1:]...)
+	assert.NoError(t, err, "must not throw errors")
+}
+
+func TestPrepareExpand_AllFields(t *testing.T) {
+	t.Parallel()
+
+	company := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id"),
+			restful.Field("name"),
+			restful.Field("city"),
+			restful.Field("revenue").RequireScopes("finance"),
+		},
+		Table: "company",
+		Where: "deleted = 0",
+	}
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id"),
+			restful.Field("name"),
+			restful.Field("companyID").QueryBy("company_id"),
+		},
+		Table: "user",
+		Relations: restful.Relations{
+			restful.Relation("company", company, "companyID", "id"),
+		},
+	}
+
+	ctx := restful.WithPrincipal(context.Background(), restful.Scopes{"finance"})
+
+	_, _, expansions, err := restful.PrepareExpandContext(ctx, cfg, restful.Request{Expand: "company"})
+	assert.NoError(t, err, "must not throw errors")
+
+	query, _, err := expansions[0].Prepare(1)
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT id, name, city, revenue FROM company WHERE (deleted = 0) AND id IN (:__restful_expand0)", query)
+
+	// The principal applies to the related resource as well, before the main rows are loaded
+	_, _, _, err = restful.PrepareExpand(cfg, restful.Request{Expand: "company(name,revenue)"})
+	assert.True(t, errors.Is(err, restful.ErrFieldForbidden))
+
+	var qe *restful.QueryError
+	if assert.True(t, errors.As(err, &qe)) {
+		assert.Equal(t, "expand", qe.Param)
+		assert.Equal(t, "revenue", qe.Token)
+		assert.Equal(t, 13, qe.Pos)
+	}
+
+	_, _, _, err = restful.PrepareExpandContext(ctx, cfg, restful.Request{Expand: "company(name,revenue)"})
+	assert.NoError(t, err, "must not throw errors")
+}
+
+func TestPrepareExpand_Errors(t *testing.T) {
+	t.Parallel()
+
+	company := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id"),
+			restful.Field("name"),
+			restful.Field("city"),
+			restful.Field("revenue").RequireScopes("finance"),
+		},
+		Table: "company",
+		Where: "deleted = 0",
+	}
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id"),
+			restful.Field("name"),
+			restful.Field("companyID").QueryBy("company_id"),
+		},
+		Table: "user",
+		Relations: restful.Relations{
+			restful.Relation("company", company, "companyID", "id"),
+		},
+	}
+
+	for expand, expected := range map[string]error{
+		"tags":                restful.ErrExpandNotAllowed,
+		"company(name":        restful.ErrExpandInvalidStructure,
+		"company)":            restful.ErrExpandInvalidStructure,
+		"company(name(id))":   restful.ErrExpandInvalidStructure,
+		"company,company":     restful.ErrExpandInvalidStructure,
+		"company(name);DROP":  restful.ErrExpandInvalidStructure,
+		"company(name),other": restful.ErrExpandNotAllowed,
+	} {
+		_, _, _, err := restful.PrepareExpand(cfg, restful.Request{Expand: expand})
+		assert.True(t, errors.Is(err, expected), expand)
+
+		_, _, err = restful.Prepare(cfg, restful.Request{Expand: expand})
+		assert.True(t, errors.Is(err, expected), expand)
+	}
+
+	// Hidden keys are never selected, the rows could not be matched
+	cfg.Fields[2] = restful.Field("companyID").QueryBy("company_id").Hidden()
+
+	_, _, err := restful.Prepare(cfg, restful.Request{Expand: "company"})
+	assert.True(t, errors.Is(err, restful.ErrExpandNotAllowed))
+}
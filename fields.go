@@ -1,7 +1,5 @@
 package restful
 
-import "fmt"
-
 // Representation of an set of fields.
 
 type OrderType int
@@ -12,15 +10,46 @@ const (
 	ASC       OrderType = iota
 )
 
+func (o OrderType) String() string {
+	if o == DESC {
+		return "DESC"
+	}
+
+	return "ASC"
+}
+
 type (
 	Fields []field
 
 	field struct {
 		Name         string
 		Query        string
+		FilterQuery  string
+		SortQuery    string
 		IsRequired   bool
 		IsSearchable bool
-		Order OrderType
+		IsNullable   bool
+		IsAggregate  bool
+		IsFilterable bool
+		IsSortable   bool
+		IsHidden     bool
+		Order        OrderType
+
+		// The type of the field, see types.go
+		Type   FieldType
+		Layout string
+		Values []string
+
+		// The allowed filter operators, see operators.go
+		AllowedOperators []Operator
+
+		// How the field matches the search terms, see search.go
+		MatchMode MatchMode
+
+		// The scopes of which the caller needs at least one, see scopes.go
+		RequiredScopes []string
+
+		forbidden bool
 	}
 )
 
@@ -29,6 +58,18 @@ func (f field) QueryBy(q string) field {
 	return f
 }
 
+// Use a different expression within WHERE or HAVING, e.g. the public name to filter on the alias
+func (f field) FilterBy(q string) field {
+	f.FilterQuery = q
+	return f
+}
+
+// Use a different expression within ORDER BY
+func (f field) SortBy(q string) field {
+	f.SortQuery = q
+	return f
+}
+
 func (f field) Required() field {
 	f.IsRequired = true
 	return f
@@ -39,20 +80,110 @@ func (f field) Searchable() field {
 	return f
 }
 
+// Allow filters on the field, even if it is hidden or the config is strict
+func (f field) Filterable() field {
+	f.IsFilterable = true
+	return f
+}
+
+// Allow to order by the field, even if it is hidden or the config is strict
+func (f field) Sortable() field {
+	f.IsSortable = true
+	return f
+}
+
+// Hidden fields are never selected. They can only be used to filter or order if they are
+// explicitly marked as Filterable or Sortable.
+func (f field) Hidden() field {
+	f.IsHidden = true
+	return f
+}
+
+// Mark the field as nullable. Inequality filters then also match NULL values.
+func (f field) Nullable() field {
+	f.IsNullable = true
+	return f
+}
+
+// Mark the field as aggregated, e.g. COUNT(o.id). Filters on it are placed into HAVING.
+// Common aggregate functions are detected automatically.
+func (f field) Aggregate() field {
+	f.IsAggregate = true
+	return f
+}
+
 // Mark this field as default order
 func (f field) OrderBy(o OrderType) field {
 	f.Order = o
 	return f
 }
 
+// Returns the field with the given name
+func (fields Fields) find(name string) (field, bool) {
+	for _, f := range fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+
+	return field{}, false
+}
+
 func Field(name string) field {
 	return field{Name: name}
 }
 
-func (f field) String() string {
-	if len(f.Query) > 0 {
-		return fmt.Sprintf("%s AS '%s'", f.Query, f.Name)
+func (f field) canFilter(strict bool) bool {
+	return f.IsFilterable || (!f.IsHidden && !strict)
+}
+
+func (f field) canSort(strict bool) bool {
+	return f.IsSortable || (!f.IsHidden && !strict)
+}
+
+// Returns the expression used in filter conditions
+func (f field) filterExpr() string {
+	if len(f.FilterQuery) > 0 {
+		return f.FilterQuery
+	}
+
+	return f.expr()
+}
+
+// Returns the expression used in the order
+func (f field) sortExpr() string {
+	if len(f.SortQuery) > 0 {
+		return f.SortQuery
+	}
+
+	return f.expr()
+}
+
+// Returns the query expression or the name of the field. Bare sub queries are wrapped, to
+// be usable within any expression.
+func (f field) expr() string {
+	if len(f.Query) == 0 {
+		return f.Name
+	}
+
+	if bareSubQueryRegex.MatchString(f.Query) {
+		return "(" + f.Query + ")"
 	}
 
-	return fmt.Sprintf("%s", f.Name)
+	return f.Query
+}
+
+// Aggregated fields are marked or detected by their filter expression, e.g. COUNT(o.id). Sub
+// queries are never considered to be aggregates, as they are evaluated on their own.
+func (f field) isAggregate() bool {
+	if f.IsAggregate {
+		return true
+	}
+
+	q := f.filterExpr()
+	return aggregateRegex.MatchString(q) && !subQueryRegex.MatchString(q)
+}
+
+func (f field) String() string {
+	return f.column().render(MySQL)
 }
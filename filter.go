@@ -0,0 +1,660 @@
+package restful
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// The filter language
+//
+//	filter  = or { "," or }
+//	or      = and { ("|" | "OR") and }
+//	and     = not { "AND" not }
+//	not     = ("!" | "NOT") not | "(" filter ")" | clause
+//	clause  = field operator value
+//
+// Besides the plain operators, the value of an "=" clause can be a list "in:(a|b)" or
+// "not-in:(a|b)", a NULL check "is:null" or "is:not-null" or a range "between:a..b". Either
+// side of a range can be left open, e.g. "between:a..".
+//
+// A comma still means AND, but separates independent requirements and therefore has the lowest
+// precedence: "a=1,b=2|c=3" equals "a=1 AND (b=2 OR c=3)". The AND keyword binds stronger than
+// OR, just like in SQL. Keywords are case insensitive and must be separated by whitespace or
+// parenthesis.
+//
+// Values may contain any character but the delimiters (whitespace, ",", "|" and ")"). Values
+// with delimiters are quoted, e.g. name="Smith, John", where "\"" is a quote and "\\" a
+// backslash. A quoted value is always compared as is, name="is:null" matches the text. Values
+// within lists and ranges can be quoted as well, e.g. in:("a, b"|c).
+
+type filterTokenKind int
+
+const (
+	tokenClause filterTokenKind = iota
+	tokenComma
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+type (
+	filterToken struct {
+		kind filterTokenKind
+		text string
+		pos  int
+
+		// The parts of a clause
+		field string
+		cmp   string
+		value string
+	}
+
+	// Node of the parsed filter. Either a logical operation with children or a single clause.
+	filterNode struct {
+		op       string
+		children []*filterNode
+		clause   filterToken
+	}
+
+	filterParser struct {
+		tokens []filterToken
+		pos    int
+
+		// The length of the filter, where a missing token is reported
+		end int
+	}
+
+	// State shared while rendering a filter
+	filterContext struct {
+		args      *map[string]interface{}
+		fields    Fields
+		dialect   Dialect
+		maxValues int
+		strict    bool
+
+		// Every clause receives its own index, to create unique argument keys
+		index int
+
+		// Tracks which kind of fields the currently rendered condition uses
+		aggregate bool
+		plain     bool
+	}
+)
+
+// Takes in a param filter string and creates the conditions of it. Also ensures that only
+// parameters are used that are part of the configured fields. Conditions on aggregated fields
+// are returned separately, as they belong into the HAVING clause. Both are a list of AND.
+func prepareFilter(filter string, args *map[string]interface{}, cfg Config) (where Condition, having Condition, err error) {
+
+	if filter == "" {
+		return And(), And(), nil
+	}
+
+	tree, err := parseFilter(filter)
+	if err != nil {
+		return Condition{}, Condition{}, err
+	}
+
+	ctx := &filterContext{
+		args:      args,
+		fields:    cfg.Fields,
+		dialect:   cfg.dialect(),
+		maxValues: cfg.MaxFilterValues,
+		strict:    cfg.Strict,
+	}
+
+	if ctx.maxValues <= 0 {
+		ctx.maxValues = FilterValuesDefault
+	}
+
+	// Every top level condition is placed separately, either into WHERE or HAVING
+	conditions := []*filterNode{tree}
+	if tree.op == "AND" {
+		conditions = tree.children
+	}
+
+	where, having = And(), And()
+
+	for _, c := range conditions {
+		ctx.aggregate, ctx.plain = false, false
+
+		cond, err := c.render(ctx)
+		if err != nil {
+			return Condition{}, Condition{}, err
+		}
+
+		switch {
+		case ctx.aggregate && ctx.plain:
+			return Condition{}, Condition{}, ErrFilterMixedAggregate
+		case ctx.aggregate:
+			having.Children = append(having.Children, cond)
+		default:
+			where.Children = append(where.Children, cond)
+		}
+	}
+
+	return where, having, nil
+}
+
+func parseFilter(filter string) (*filterNode, error) {
+	tokens, err := lexFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	p := filterParser{tokens: tokens, end: len([]rune(filter))}
+
+	node, err := p.parseFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	// Anything left is not part of the structure, e.g. a closing parenthesis without an opening one
+	if p.pos != len(p.tokens) {
+		return nil, p.fail(p.pos)
+	}
+
+	return node, nil
+}
+
+// Splits the filter into clauses and logical tokens.
+func lexFilter(filter string) ([]filterToken, error) {
+	tokens := []filterToken{}
+	runes := []rune(filter)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == ',':
+			tokens = append(tokens, filterToken{kind: tokenComma, text: ",", pos: i})
+			i++
+			continue
+		case r == '|':
+			tokens = append(tokens, filterToken{kind: tokenOr, text: "|", pos: i})
+			i++
+			continue
+		case r == '!':
+			tokens = append(tokens, filterToken{kind: tokenNot, text: "!", pos: i})
+			i++
+			continue
+		case r == '(':
+			tokens = append(tokens, filterToken{kind: tokenOpen, text: "(", pos: i})
+			i++
+			continue
+		case r == ')':
+			tokens = append(tokens, filterToken{kind: tokenClose, text: ")", pos: i})
+			i++
+			continue
+		}
+
+		if kind, n := lexKeyword(runes[i:]); n > 0 {
+			tokens = append(tokens, filterToken{kind: kind, text: string(runes[i : i+n]), pos: i})
+			i += n
+			continue
+		}
+
+		// Anything else is a clause
+		t, n, err := lexClause(runes[i:])
+		if err != nil {
+			return nil, newQueryError("filter", clauseText(runes[i:]), i, err)
+		}
+
+		t.pos = i
+		tokens = append(tokens, t)
+		i += n
+	}
+
+	return tokens, nil
+}
+
+// Lexes the "field operator value" clause at the beginning of the given input and returns its
+// length. The value runs until the next delimiter, parenthesis and quoted parts within the value
+// belong to it.
+func lexClause(runes []rune) (filterToken, int, error) {
+	t := filterToken{kind: tokenClause}
+
+	i := 0
+	for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+		i++
+	}
+
+	if i == 0 {
+		return t, 0, ErrFilterStructure
+	}
+
+	t.field = string(runes[:i])
+
+	// Longer operators must come first, otherwise "<" would shadow "<=", ">=" and "<>".
+	for _, cmp := range []string{"<=", ">=", "<>", "!=", "~=", "=", "<", ">"} {
+		if strings.HasPrefix(string(runes[i:]), cmp) {
+			t.cmp = cmp
+			break
+		}
+	}
+
+	if len(t.cmp) == 0 {
+		return t, 0, ErrFilterStructure
+	}
+
+	i += len(t.cmp)
+	start, depth := i, 0
+
+	for ; i < len(runes); i++ {
+		r := runes[i]
+
+		if r == '"' {
+			end, err := closingQuote(runes, i)
+			if err != nil {
+				return t, 0, err
+			}
+
+			i = end
+		} else if r == '(' {
+			depth++
+		} else if r == ')' && depth > 0 {
+			depth--
+		} else if depth == 0 && (r == ',' || r == '|' || r == ')' || unicode.IsSpace(r)) {
+			break
+		}
+	}
+
+	if depth > 0 || i == start {
+		return t, 0, ErrFilterStructure
+	}
+
+	t.value = string(runes[start:i])
+	t.text = string(runes[:i])
+
+	return t, i, nil
+}
+
+// Returns the text up to the next whitespace, comma or pipe, to report a malformed clause.
+func clauseText(runes []rune) string {
+	for i, r := range runes {
+		if r == ',' || r == '|' || unicode.IsSpace(r) {
+			return string(runes[:i])
+		}
+	}
+
+	return string(runes)
+}
+
+// Returns the index of the quote that closes the quoted part starting at the given index.
+func closingQuote(runes []rune, start int) (int, error) {
+	for i := start + 1; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			i++
+		case '"':
+			return i, nil
+		}
+	}
+
+	return 0, ErrFilterStructure
+}
+
+// Returns the value without the quotes. Within quotes, "\"" is a quote and "\\" a backslash,
+// other escapes (e.g. "\*" for LIKE) are kept. Unquoted values must not contain quotes.
+func unquote(value string) (string, error) {
+	if !strings.HasPrefix(value, `"`) {
+		if strings.ContainsRune(value, '"') {
+			return "", ErrFilterStructure
+		}
+
+		return value, nil
+	}
+
+	runes := []rune(value)
+
+	end, err := closingQuote(runes, 0)
+	if err != nil || end != len(runes)-1 {
+		return "", ErrFilterStructure
+	}
+
+	out := strings.Builder{}
+	for i := 1; i < end; i++ {
+		if runes[i] == '\\' && (runes[i+1] == '"' || runes[i+1] == '\\') {
+			i++
+		}
+
+		out.WriteRune(runes[i])
+	}
+
+	return out.String(), nil
+}
+
+// Splits the value by the separator, that is not part of a quoted value.
+func splitQuoted(value string, sep string) []string {
+	parts := []string{}
+	runes := []rune(value)
+	start := 0
+
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '"' {
+			if end, err := closingQuote(runes, i); err == nil {
+				i = end
+			}
+
+			continue
+		}
+
+		if strings.HasPrefix(string(runes[i:]), sep) {
+			parts = append(parts, string(runes[start:i]))
+			i += len(sep) - 1
+			start = i + 1
+		}
+	}
+
+	return append(parts, string(runes[start:]))
+}
+
+// Returns the keyword at the beginning of the given input and its length.
+func lexKeyword(runes []rune) (filterTokenKind, int) {
+	keywords := []struct {
+		word string
+		kind filterTokenKind
+	}{
+		{"AND", tokenAnd},
+		{"OR", tokenOr},
+		{"NOT", tokenNot},
+	}
+
+	for _, k := range keywords {
+		n := len(k.word)
+		if len(runes) < n || !strings.EqualFold(string(runes[:n]), k.word) {
+			continue
+		}
+
+		if len(runes) == n || unicode.IsSpace(runes[n]) || runes[n] == '(' {
+			return k.kind, n
+		}
+	}
+
+	return 0, 0
+}
+
+func (p *filterParser) peek() (filterToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return filterToken{}, false
+	}
+
+	return p.tokens[p.pos], true
+}
+
+func (p *filterParser) parseFilter() (*filterNode, error) {
+	return p.parseList("AND", tokenComma, p.parseOr)
+}
+
+func (p *filterParser) parseOr() (*filterNode, error) {
+	return p.parseList("OR", tokenOr, p.parseAnd)
+}
+
+func (p *filterParser) parseAnd() (*filterNode, error) {
+	return p.parseList("AND", tokenAnd, p.parseNot)
+}
+
+// Parses a list of operands separated by the given operator.
+func (p *filterParser) parseList(op string, kind filterTokenKind, next func() (*filterNode, error)) (*filterNode, error) {
+	first, err := next()
+	if err != nil {
+		return nil, err
+	}
+
+	node := &filterNode{op: op, children: []*filterNode{first}}
+
+	for {
+		t, ok := p.peek()
+		if !ok || t.kind != kind {
+			break
+		}
+
+		p.pos++
+
+		child, err := next()
+		if err != nil {
+			return nil, err
+		}
+
+		node.children = append(node.children, child)
+	}
+
+	if len(node.children) == 1 {
+		return first, nil
+	}
+
+	return node, nil
+}
+
+// Returns the structure error at the token with the given index, or at the end of the filter.
+func (p *filterParser) fail(i int) error {
+	if i >= len(p.tokens) {
+		return newQueryError("filter", "", p.end, ErrFilterStructure)
+	}
+
+	return newQueryError("filter", p.tokens[i].text, p.tokens[i].pos, ErrFilterStructure)
+}
+
+func (p *filterParser) parseNot() (*filterNode, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, p.fail(p.pos)
+	}
+
+	p.pos++
+
+	switch t.kind {
+	case tokenNot:
+		child, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return &filterNode{op: "NOT", children: []*filterNode{child}}, nil
+
+	case tokenOpen:
+		child, err := p.parseFilter()
+		if err != nil {
+			return nil, err
+		}
+
+		if t, ok := p.peek(); !ok || t.kind != tokenClose {
+			return nil, p.fail(p.pos)
+		}
+
+		p.pos++
+		return child, nil
+
+	case tokenClause:
+		return &filterNode{clause: t}, nil
+	}
+
+	return nil, p.fail(p.pos - 1)
+}
+
+// Renders the node into a condition tree.
+func (n *filterNode) render(ctx *filterContext) (Condition, error) {
+
+	if len(n.op) == 0 {
+		sql, err := ctx.renderClause(n.clause)
+		if err != nil {
+			return Condition{}, newQueryError("filter", n.clause.text, n.clause.pos, err)
+		}
+
+		return Raw(sql), nil
+	}
+
+	children := make([]Condition, len(n.children))
+	for i, c := range n.children {
+		child, err := c.render(ctx)
+		if err != nil {
+			return Condition{}, err
+		}
+
+		children[i] = child
+	}
+
+	return Condition{Op: n.op, Children: children}, nil
+}
+
+// Renders a single "field operator value" clause and adds the value to the arguments.
+func (ctx *filterContext) renderClause(t filterToken) (string, error) {
+
+	// make sure that the given parameter is part of the valid list
+	param, cmp, value := t.field, t.cmp, t.value
+
+	f, isValid := ctx.fields.find(param)
+	if !isValid || !f.canFilter(ctx.strict) {
+		return "", ErrFilterNotAllowed
+	}
+
+	if f.forbidden {
+		return "", ErrFieldForbidden
+	}
+
+	if f.isAggregate() {
+		ctx.aggregate = true
+	} else {
+		ctx.plain = true
+	}
+
+	op, value := clauseOperator(cmp, value)
+	if !f.allows(op) {
+		return "", &OperatorError{Field: f.Name, Operator: op}
+	}
+
+	// The key only depends on the position of the clause, so it can neither clash with
+	// another clause nor with the additional params
+	key := fmt.Sprintf("__restful_filter%d", ctx.index)
+	ctx.index++
+
+	switch op {
+	case OpIn:
+		return ctx.renderList(f, "IN", key, value)
+	case OpNotIn:
+		return ctx.renderList(f, "NOT IN", key, value)
+	case OpBetween:
+		return ctx.renderRange(f, key, value)
+
+	// NULL checks do not need an argument at all
+	case OpNull:
+		return fmt.Sprintf("%s IS NULL", f.filterExpr()), nil
+	case OpNotNull:
+		return fmt.Sprintf("%s IS NOT NULL", f.filterExpr()), nil
+
+	}
+
+	value, err := unquote(value)
+	if err != nil {
+		return "", err
+	}
+
+	if op == OpLike {
+		// The value is matched like a search term, see likePattern
+		(*ctx.args)[key] = MatchContains.pattern(value)
+
+		return like(ctx.dialect, f.filterExpr(), key), nil
+	}
+
+	v, err := f.parse(value)
+	if err != nil {
+		return "", err
+	}
+
+	(*ctx.args)[key] = v
+
+	if op == OpNe && f.IsNullable {
+		return ctx.dialect.DistinctFrom(f.filterExpr(), ":"+key), nil
+	}
+
+	return fmt.Sprintf("%s %s :%s", f.filterExpr(), cmp, key), nil
+}
+
+// Renders a list of values, e.g. "(open|pending)", into an IN condition with one argument per value.
+func (ctx *filterContext) renderList(f field, op string, key string, list string) (string, error) {
+
+	if len(list) < 2 || list[0] != '(' || list[len(list)-1] != ')' {
+		return "", ErrFilterStructure
+	}
+
+	values := splitQuoted(list[1:len(list)-1], "|")
+	if len(values) > ctx.maxValues {
+		return "", ErrFilterTooManyValues
+	}
+
+	placeholders := make([]string, len(values))
+	for i, raw := range values {
+		if len(raw) == 0 {
+			return "", ErrFilterStructure
+		}
+
+		v, err := unquote(raw)
+		if err != nil {
+			return "", err
+		}
+
+		parsed, err := f.parse(v)
+		if err != nil {
+			return "", err
+		}
+
+		k := fmt.Sprintf("%s_%d", key, i)
+		(*ctx.args)[k] = parsed
+		placeholders[i] = ":" + k
+	}
+
+	return fmt.Sprintf("%s %s (%s)", f.filterExpr(), op, strings.Join(placeholders, ", ")), nil
+}
+
+// Renders a range "from..to" into a BETWEEN condition. An open side results in a simple comparison.
+func (ctx *filterContext) renderRange(f field, key string, bounds string) (string, error) {
+
+	parts := splitQuoted(bounds, "..")
+	if len(parts) != 2 || (len(parts[0]) == 0 && len(parts[1]) == 0) {
+		return "", ErrFilterStructure
+	}
+
+	from, to := key+"_from", key+"_to"
+
+	for i, k := range []string{from, to} {
+		if len(parts[i]) == 0 {
+			continue
+		}
+
+		raw, err := unquote(parts[i])
+		if err != nil {
+			return "", err
+		}
+
+		v, err := f.parse(raw)
+		if err != nil {
+			return "", err
+		}
+
+		(*ctx.args)[k] = v
+	}
+
+	switch {
+	case len(parts[1]) == 0:
+		return fmt.Sprintf("%s >= :%s", f.filterExpr(), from), nil
+	case len(parts[0]) == 0:
+		return fmt.Sprintf("%s <= :%s", f.filterExpr(), to), nil
+	}
+
+	return fmt.Sprintf("%s BETWEEN :%s AND :%s", f.filterExpr(), from, to), nil
+}
+
+// Returns the string without the prefix and whether the prefix was found.
+func cutPrefix(s, prefix string) (string, bool) {
+	if !strings.HasPrefix(s, prefix) {
+		return s, false
+	}
+
+	return s[len(prefix):], true
+}
@@ -0,0 +1,414 @@
+package restful_test
+
+import (
+	"errors"
+	"github.com/joernlenoch/go-restful"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFilter_Or(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("status"),
+			restful.Field("age"),
+			restful.Field("name"),
+		},
+		Table: "user",
+	}
+
+	query, args, err := restful.Prepare(cfg, restful.Request{
+		Fields: "name",
+		Filter: "status=open|status=pending",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name FROM user WHERE (status = :__restful_filter0 OR status = :__restful_filter1) LIMIT 50", query)
+	assert.Equal(t, map[string]interface{}{"__restful_filter0": "open", "__restful_filter1": "pending"}, args)
+
+	query, _, err = restful.Prepare(cfg, restful.Request{
+		Fields: "name",
+		Filter: "status=open or status=pending",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name FROM user WHERE (status = :__restful_filter0 OR status = :__restful_filter1) LIMIT 50", query)
+}
+
+func TestFilter_Precedence(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("status"),
+			restful.Field("age"),
+			restful.Field("name"),
+		},
+		Table: "user",
+	}
+
+	query, _, err := restful.Prepare(cfg, restful.Request{
+		Fields: "name",
+		Filter: "age>3,status=open|status=pending",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name FROM user WHERE age > :__restful_filter0 AND (status = :__restful_filter1 OR status = :__restful_filter2) LIMIT 50", query)
+
+	query, _, err = restful.Prepare(cfg, restful.Request{
+		Fields: "name",
+		Filter: "(age>3 AND status=open) | name~=a",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name FROM user WHERE ((age > :__restful_filter0 AND status = :__restful_filter1) OR name LIKE :__restful_filter2 ESCAPE '!') LIMIT 50", query)
+}
+
+func TestFilter_Not(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("status"),
+			restful.Field("age"),
+			restful.Field("name"),
+		},
+		Table: "user",
+	}
+
+	query, _, err := restful.Prepare(cfg, restful.Request{
+		Fields: "name",
+		Filter: "NOT (status=open|status=pending),!age=3",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name FROM user WHERE NOT (status = :__restful_filter0 OR status = :__restful_filter1) AND NOT (age = :__restful_filter2) LIMIT 50", query)
+}
+
+func TestFilter_Structure(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("status"),
+			restful.Field("age"),
+			restful.Field("name"),
+		},
+		Table: "user",
+	}
+
+	for _, filter := range []string{
+		"(status=open",
+		"status=open)",
+		"status=open|",
+		"status=open age=3",
+		"NOT",
+		"()",
+	} {
+		_, _, err := restful.Prepare(cfg, restful.Request{Filter: filter})
+		assert.True(t, errors.Is(err, restful.ErrFilterStructure), filter)
+	}
+
+	_, _, err := restful.Prepare(cfg, restful.Request{Filter: "status=open|password=x"})
+	assert.True(t, errors.Is(err, restful.ErrFilterNotAllowed))
+}
+
+func TestFilter_In(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("status"),
+			restful.Field("age"),
+			restful.Field("name"),
+		},
+		Table: "user",
+	}
+
+	query, args, err := restful.Prepare(cfg, restful.Request{
+		Fields: "name",
+		Filter: "status=in:(open|pending|closed),age=not-in:(1|2)",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name FROM user WHERE status IN (:__restful_filter0_0, :__restful_filter0_1, :__restful_filter0_2) AND age NOT IN (:__restful_filter1_0, :__restful_filter1_1) LIMIT 50", query)
+	assert.Equal(t, map[string]interface{}{
+		"__restful_filter0_0": "open",
+		"__restful_filter0_1": "pending",
+		"__restful_filter0_2": "closed",
+		"__restful_filter1_0": "1",
+		"__restful_filter1_1": "2",
+	}, args)
+
+	query, _, err = restful.PrepareCount(cfg, restful.Request{
+		Filter: "status=in:(open)|age=1",
+	}, "*")
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT COUNT(*) FROM user WHERE (status IN (:__restful_filter0_0) OR age = :__restful_filter1)", query)
+}
+
+func TestFilter_InStructure(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("status"),
+			restful.Field("age"),
+			restful.Field("name"),
+		},
+		Table: "user",
+	}
+
+	for _, filter := range []string{
+		"status=in:open",
+		"status=in:(open|)",
+		"status=in:()",
+		"status=in:(open",
+	} {
+		_, _, err := restful.Prepare(cfg, restful.Request{Filter: filter})
+		assert.True(t, errors.Is(err, restful.ErrFilterStructure), filter)
+	}
+
+	cfg.MaxFilterValues = 2
+
+	_, _, err := restful.Count(cfg, restful.Request{Filter: "status=in:(a|b|c)"})
+	assert.True(t, errors.Is(err, restful.ErrFilterTooManyValues))
+}
+
+func TestFilter_Null(t *testing.T) {
+	t.Parallel()
+
+	query, args, err := restful.Prepare(restful.Config{
+		Fields: restful.Fields{
+			restful.Field("name"),
+			restful.Field("deleted_at"),
+			restful.Field("manager_id").QueryBy("u.manager_id").Nullable(),
+		},
+		Table: "user u",
+	}, restful.Request{
+		Fields: "name",
+		Filter: "deleted_at=is:null,manager_id=is:not-null|manager_id!=4",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name FROM user u WHERE deleted_at IS NULL AND (u.manager_id IS NOT NULL OR NOT (u.manager_id <=> :__restful_filter2)) LIMIT 50", query)
+	assert.Equal(t, map[string]interface{}{"__restful_filter2": "4"}, args)
+}
+
+func TestFilter_NullSafe(t *testing.T) {
+	t.Parallel()
+
+	query, _, err := restful.Prepare(restful.Config{
+		Fields: restful.Fields{
+			restful.Field("name"),
+			restful.Field("manager_id").Nullable(),
+		},
+		Table:   "user",
+		Dialect: restful.PostgreSQL,
+	}, restful.Request{
+		Fields: "name",
+		Filter: "manager_id<>4,name!=x",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name FROM user WHERE manager_id IS DISTINCT FROM :__restful_filter0 AND name != :__restful_filter1 LIMIT 50", query)
+
+	// Older versions of SQLite and SQL Server do not support IS DISTINCT FROM
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("manager_id").Nullable(),
+		},
+		Table:   "user",
+		Dialect: restful.SQLite,
+	}
+
+	query, _, err = restful.Prepare(cfg, restful.Request{Filter: "manager_id<>4"})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT manager_id FROM user WHERE manager_id IS NOT :__restful_filter0 LIMIT 50", query)
+
+	cfg.Dialect = restful.SQLServer
+	query, _, err = restful.Count(cfg, restful.Request{Filter: "manager_id<>4"})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT COUNT(*) FROM (SELECT manager_id FROM user WHERE (manager_id <> :__restful_filter0 OR "+
+		"(manager_id IS NULL AND :__restful_filter0 IS NOT NULL) OR (manager_id IS NOT NULL AND :__restful_filter0 IS NULL))) t", query)
+}
+
+func TestFilter_Between(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("name"),
+			restful.Field("created"),
+			restful.Field("amount"),
+		},
+		Table: "invoice",
+	}
+
+	query, args, err := restful.Prepare(cfg, restful.Request{
+		Fields: "name",
+		Filter: "created=between:2024-01-01..2024-02-01,amount=between:10..,amount=between:..20.5",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name FROM invoice WHERE created BETWEEN :__restful_filter0_from AND :__restful_filter0_to "+
+		"AND amount >= :__restful_filter1_from AND amount <= :__restful_filter2_to LIMIT 50", query)
+	assert.Equal(t, map[string]interface{}{
+		"__restful_filter0_from": "2024-01-01",
+		"__restful_filter0_to":   "2024-02-01",
+		"__restful_filter1_from": "10",
+		"__restful_filter2_to":   "20.5",
+	}, args)
+
+	for _, filter := range []string{"created=between:..", "created=between:2024", "created=between:1..2..3"} {
+		_, _, err = restful.Prepare(cfg, restful.Request{Filter: filter})
+		assert.True(t, errors.Is(err, restful.ErrFilterStructure), filter)
+	}
+}
+
+func TestFilter_Operators(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("status"),
+			restful.Field("age"),
+			restful.Field("name"),
+		},
+		Table: "user",
+	}
+
+	query, args, err := restful.Prepare(cfg, restful.Request{
+		Fields: "name",
+		Filter: "age<=3,age>=1,age<>2,age<9,age>0",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name FROM user WHERE age <= :__restful_filter0 AND age >= :__restful_filter1 AND age <> :__restful_filter2 AND age < :__restful_filter3 AND age > :__restful_filter4 LIMIT 50", query)
+	assert.Equal(t, "3", args["__restful_filter0"], "must not keep the operator within the value")
+	assert.Equal(t, "1", args["__restful_filter1"], "must not keep the operator within the value")
+}
+
+func TestFilter_Keys(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("a"),
+			restful.Field("a1"),
+			restful.Field("tenant"),
+		},
+		Table:            "t",
+		Where:            "tenant = :tenant",
+		AdditionalParams: restful.Params{"tenant": 7},
+	}
+
+	// The 11th clause of "a" must not overwrite the 2nd clause of "a1"
+	query, args, err := restful.Prepare(cfg, restful.Request{
+		Fields: "a",
+		Filter: "tenant=1,a1=x,a=0,a=0,a=0,a=0,a=0,a=0,a=0,a=0,a=y",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Contains(t, query, "a1 = :__restful_filter1 AND")
+	assert.Contains(t, query, "a = :__restful_filter10 LIMIT 50")
+	assert.Equal(t, 7, args["tenant"])
+	assert.Equal(t, "1", args["__restful_filter0"])
+	assert.Equal(t, "x", args["__restful_filter1"])
+	assert.Equal(t, "y", args["__restful_filter10"])
+}
+
+func TestFilter_LikeEscaping(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("status"),
+			restful.Field("age"),
+			restful.Field("name"),
+		},
+		Table: "user",
+	}
+
+	query, args, err := restful.Prepare(cfg, restful.Request{
+		Fields: "name",
+		Filter: `name~=user_*,name~=a\*b`,
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name FROM user WHERE name LIKE :__restful_filter0 ESCAPE '!' AND name LIKE :__restful_filter1 ESCAPE '!' LIMIT 50", query)
+	assert.Equal(t, "%user!_%%", args["__restful_filter0"])
+	assert.Equal(t, "%a*b%", args["__restful_filter1"])
+
+	// SQL Server matches character classes with brackets
+	cfg.Dialect = restful.SQLServer
+
+	query, args, err = restful.Prepare(cfg, restful.Request{
+		Fields: "name",
+		Filter: "name~=[a-z]_*",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name FROM user WHERE name LIKE :__restful_filter0 ESCAPE '!' ORDER BY (SELECT NULL) OFFSET 0 ROWS FETCH NEXT 50 ROWS ONLY", query)
+	assert.Equal(t, "%![a-z]!_%%", args["__restful_filter0"])
+}
+
+func TestFilter_Quoted(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("status"),
+			restful.Field("age"),
+			restful.Field("name"),
+		},
+		Table: "user",
+	}
+
+	query, args, err := restful.Prepare(cfg, restful.Request{
+		Fields: "name",
+		Filter: `name="Smith, John"|name=São-Paulo,status="is:null",age=in:("a, b"|"c \"d\""|e),name="back\\slash",name=anna@example.com`,
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name FROM user WHERE (name = :__restful_filter0 OR name = :__restful_filter1) AND status = :__restful_filter2 "+
+		"AND age IN (:__restful_filter3_0, :__restful_filter3_1, :__restful_filter3_2) AND name = :__restful_filter4 AND name = :__restful_filter5 LIMIT 50", query)
+	assert.Equal(t, map[string]interface{}{
+		"__restful_filter0":   "Smith, John",
+		"__restful_filter1":   "São-Paulo",
+		"__restful_filter2":   "is:null",
+		"__restful_filter3_0": "a, b",
+		"__restful_filter3_1": `c "d"`,
+		"__restful_filter3_2": "e",
+		"__restful_filter4":   `back\slash`,
+		"__restful_filter5":   "anna@example.com",
+	}, args)
+
+	query, args, err = restful.Prepare(cfg, restful.Request{
+		Fields: "name",
+		Filter: `name~="50% off",age=between:"1 0".."2 0"`,
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name FROM user WHERE name LIKE :__restful_filter0 ESCAPE '!' AND age BETWEEN :__restful_filter1_from AND :__restful_filter1_to LIMIT 50", query)
+	assert.Equal(t, "%50!% off%", args["__restful_filter0"])
+	assert.Equal(t, "1 0", args["__restful_filter1_from"])
+	assert.Equal(t, "2 0", args["__restful_filter1_to"])
+
+	for _, filter := range []string{
+		`name="open`,
+		`name="a"b`,
+		`name=a"b"`,
+		`name=`,
+		`=a`,
+		`name"=a"`,
+	} {
+		_, _, err := restful.Prepare(cfg, restful.Request{Filter: filter})
+		assert.True(t, errors.Is(err, restful.ErrFilterStructure), filter)
+	}
+}
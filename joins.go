@@ -0,0 +1,92 @@
+package restful
+
+import (
+	"fmt"
+	"strings"
+)
+
+type JoinType int
+
+const (
+	JoinInner JoinType = iota
+	JoinLeft  JoinType = iota
+	JoinRight JoinType = iota
+)
+
+type (
+	Joins []join
+
+	// Declaration of a joined table. Fields of joined tables are declared with their
+	// qualified column, e.g. Field("company").QueryBy("c.name").
+	join struct {
+		Type      JoinType
+		Table     string
+		Alias     string
+		Condition string
+		Columns   []string
+	}
+)
+
+func InnerJoin(table string, alias string) join {
+	return join{Type: JoinInner, Table: table, Alias: alias}
+}
+
+func LeftJoin(table string, alias string) join {
+	return join{Type: JoinLeft, Table: table, Alias: alias}
+}
+
+func RightJoin(table string, alias string) join {
+	return join{Type: JoinRight, Table: table, Alias: alias}
+}
+
+// Join the table on the given condition
+func (j join) On(cond string) join {
+	j.Condition = cond
+	j.Columns = nil
+	return j
+}
+
+// Join the table on the given shared columns
+func (j join) Using(columns ...string) join {
+	j.Columns = columns
+	j.Condition = ""
+	return j
+}
+
+func (t JoinType) String() string {
+	switch t {
+	case JoinLeft:
+		return "LEFT JOIN"
+	case JoinRight:
+		return "RIGHT JOIN"
+	}
+
+	return "INNER JOIN"
+}
+
+func (j join) String() string {
+	out := fmt.Sprintf("%s %s", j.Type, j.Table)
+
+	if len(j.Alias) > 0 {
+		out += " " + j.Alias
+	}
+
+	if len(j.Columns) > 0 {
+		return fmt.Sprintf("%s USING (%s)", out, strings.Join(j.Columns, ", "))
+	}
+
+	if len(j.Condition) > 0 {
+		return fmt.Sprintf("%s ON %s", out, j.Condition)
+	}
+
+	return out
+}
+
+func (j Joins) String() string {
+	parts := make([]string, len(j))
+	for i, v := range j {
+		parts[i] = v.String()
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,69 @@
+package restful_test
+
+import (
+	"github.com/joernlenoch/go-restful"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPrepare_Joins(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id").QueryBy("u.id"),
+			restful.Field("company").QueryBy("c.name").Searchable(),
+		},
+		Table: "user u",
+		Joins: restful.Joins{
+			restful.LeftJoin("company", "c").Using("company_id"),
+			restful.InnerJoin("team", "t").On("t.id = u.team_id"),
+		},
+	}
+
+	query, args, err := restful.Prepare(cfg, restful.Request{
+		Filter: "company=acme",
+		Order:  "-company",
+		Search: "ac",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT u.id AS 'id', c.name AS 'company' FROM user u LEFT JOIN company c USING (company_id) "+
+		"INNER JOIN team t ON t.id = u.team_id WHERE c.name = :__restful_filter0 AND c.name LIKE :__restful_search ESCAPE '!' ORDER BY c.name DESC LIMIT 50", query)
+	assert.Equal(t, "acme", args["__restful_filter0"])
+}
+
+func TestCount_Joins(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id").QueryBy("u.id"),
+			restful.Field("company").QueryBy("c.name").Searchable(),
+		},
+		Table: "user u",
+		Joins: restful.Joins{
+			restful.LeftJoin("company", "c").Using("company_id"),
+			restful.InnerJoin("team", "t").On("t.id = u.team_id"),
+		},
+	}
+
+	query, _, err := restful.Count(cfg, restful.Request{Filter: "company=acme"})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT COUNT(*) FROM (SELECT u.id AS 'id', c.name AS 'company' FROM user u LEFT JOIN company c USING (company_id) "+
+		"INNER JOIN team t ON t.id = u.team_id WHERE c.name = :__restful_filter0) t", query)
+
+	query, _, err = restful.PrepareCount(cfg, restful.Request{Filter: "company=acme"}, "*")
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT COUNT(*) FROM user u LEFT JOIN company c USING (company_id) "+
+		"INNER JOIN team t ON t.id = u.team_id WHERE c.name = :__restful_filter0", query)
+}
+
+func TestJoin_String(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "RIGHT JOIN company", restful.RightJoin("company", "").String())
+	assert.Equal(t, "INNER JOIN company c ON c.id = u.company_id", restful.InnerJoin("company", "c").Using("x").On("c.id = u.company_id").String())
+}
@@ -0,0 +1,56 @@
+package restful
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NamingStrategy maps the public name of a field to its column. It is applied to all fields
+// that do not define their own query.
+type NamingStrategy func(name string) string
+
+// SnakeCase maps camelCase names to snake_case columns, e.g. "userID" to "user_id".
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	out := strings.Builder{}
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				prev := runes[i-1]
+				nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+				if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+					out.WriteRune('_')
+				}
+			}
+
+			r = unicode.ToLower(r)
+		}
+
+		out.WriteRune(r)
+	}
+
+	return out.String()
+}
+
+// Returns the config with the naming strategy applied to all fields.
+func (cfg Config) named() Config {
+	if cfg.Naming == nil {
+		return cfg
+	}
+
+	fields := make(Fields, len(cfg.Fields))
+	for i, f := range cfg.Fields {
+		if len(f.Query) == 0 {
+			if column := cfg.Naming(f.Name); column != f.Name {
+				f.Query = column
+			}
+		}
+
+		fields[i] = f
+	}
+
+	cfg.Fields = fields
+	return cfg
+}
@@ -0,0 +1,52 @@
+package restful_test
+
+import (
+	"errors"
+	"github.com/joernlenoch/go-restful"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPrepare_Naming(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id"),
+			restful.Field("firstName").Searchable(),
+			restful.Field("companyID").QueryBy("c.id"),
+		},
+		Table:  "user",
+		Naming: restful.SnakeCase,
+	}
+
+	query, args, err := restful.Prepare(cfg, restful.Request{
+		Fields: "id,firstName",
+		Filter: "firstName=anna",
+		Order:  "-firstName",
+		Search: "an",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT id, first_name AS 'firstName' FROM user WHERE first_name = :__restful_filter0 "+
+		"AND first_name LIKE :__restful_search ESCAPE '!' ORDER BY first_name DESC LIMIT 50", query)
+	assert.Equal(t, "anna", args["__restful_filter0"])
+
+	_, _, err = restful.Prepare(cfg, restful.Request{Filter: "first_name=anna"})
+	assert.True(t, errors.Is(err, restful.ErrFilterNotAllowed), "must not expose the column")
+}
+
+func TestSnakeCase(t *testing.T) {
+	t.Parallel()
+
+	for in, out := range map[string]string{
+		"name":         "name",
+		"firstName":    "first_name",
+		"userID":       "user_id",
+		"HTTPServer":   "http_server",
+		"address2City": "address2_city",
+		"already_done": "already_done",
+	} {
+		assert.Equal(t, out, restful.SnakeCase(in), in)
+	}
+}
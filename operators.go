@@ -0,0 +1,111 @@
+package restful
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrOperatorNotAllowed = errors.New("the filter operator is not allowed")
+)
+
+// A filter operator, as used within the filter string.
+type Operator string
+
+const (
+	OpEq      Operator = "="
+	OpNe      Operator = "!="
+	OpLike    Operator = "~="
+	OpLt      Operator = "<"
+	OpGt      Operator = ">"
+	OpLte     Operator = "<="
+	OpGte     Operator = ">="
+	OpIn      Operator = "in"
+	OpNotIn   Operator = "not-in"
+	OpNull    Operator = "is:null"
+	OpNotNull Operator = "is:not-null"
+	OpBetween Operator = "between"
+)
+
+var (
+	comparableOperators = []Operator{OpEq, OpNe, OpLt, OpGt, OpLte, OpGte, OpIn, OpNotIn, OpBetween, OpNull, OpNotNull}
+	identityOperators   = []Operator{OpEq, OpNe, OpIn, OpNotIn, OpNull, OpNotNull}
+	boolOperators       = []Operator{OpEq, OpNe, OpNull, OpNotNull}
+
+	// The operators of fields that do not declare their own ones
+	defaultOperators = map[FieldType][]Operator{
+		TypeInt:     comparableOperators,
+		TypeDecimal: comparableOperators,
+		TypeTime:    comparableOperators,
+		TypeBool:    boolOperators,
+		TypeUUID:    identityOperators,
+		TypeEnum:    identityOperators,
+	}
+)
+
+// OperatorError is returned when a filter uses an operator that the field does not allow.
+type OperatorError struct {
+	Field    string
+	Operator Operator
+}
+
+func (e *OperatorError) Error() string {
+	return fmt.Sprintf("the operator %q is not allowed for the field %s", e.Operator, e.Field)
+}
+
+func (e *OperatorError) Unwrap() error {
+	return ErrOperatorNotAllowed
+}
+
+// Restrict the filter operators of the field. Without a restriction, the operators depend on the
+// type of the field: text fields allow all operators, while e.g. numbers do not allow LIKE.
+func (f field) Operators(ops ...Operator) field {
+	f.AllowedOperators = ops
+	return f
+}
+
+func (f field) allows(op Operator) bool {
+	allowed := f.AllowedOperators
+	if allowed == nil {
+		var ok bool
+		if allowed, ok = defaultOperators[f.Type]; !ok {
+			return true
+		}
+	}
+
+	for _, o := range allowed {
+		if o == op {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Detects the operator of a clause. Special operators are part of the value of an "=" clause
+// (e.g. "in:(a|b)"), the remaining value is returned as well.
+func clauseOperator(cmp string, value string) (Operator, string) {
+
+	if cmp == "<>" {
+		return OpNe, value
+	}
+
+	// Quoted values are never interpreted
+	if cmp != "=" || strings.HasPrefix(value, `"`) {
+		return Operator(cmp), value
+	}
+
+	for _, op := range []Operator{OpIn, OpNotIn, OpBetween} {
+		if rest, ok := cutPrefix(value, string(op)+":"); ok {
+			return op, rest
+		}
+	}
+
+	switch Operator(value) {
+	case OpNull, OpNotNull:
+		return Operator(value), ""
+	}
+
+	return OpEq, value
+}
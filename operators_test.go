@@ -0,0 +1,71 @@
+package restful_test
+
+import (
+	"errors"
+	"github.com/joernlenoch/go-restful"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestOperators_Allowed(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id").Operators(restful.OpEq, restful.OpIn),
+			restful.Field("name"),
+			restful.Field("age").Int(),
+			restful.Field("active").Bool(),
+			restful.Field("status").Enum("open", "closed"),
+		},
+		Table: "user",
+	}
+
+	query, _, err := restful.Prepare(cfg, restful.Request{
+		Fields: "id",
+		Filter: "id=in:(1|2),name~=a,age=between:1..5,active=is:null,status!=open",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT id FROM user WHERE id IN (:__restful_filter0_0, :__restful_filter0_1) AND name LIKE :__restful_filter1 ESCAPE '!' AND age BETWEEN :__restful_filter2_from AND :__restful_filter2_to "+
+		"AND active IS NULL AND status != :__restful_filter4 LIMIT 50", query)
+}
+
+func TestOperators_NotAllowed(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id").Operators(restful.OpEq, restful.OpIn),
+			restful.Field("name"),
+			restful.Field("age").Int(),
+			restful.Field("active").Bool(),
+			restful.Field("status").Enum("open", "closed"),
+		},
+		Table: "user",
+	}
+
+	for filter, op := range map[string]restful.Operator{
+		"id>1":                    restful.OpGt,
+		"id=not-in:(1)":           restful.OpNotIn,
+		"id=is:not-null":          restful.OpNotNull,
+		"age~=1":                  restful.OpLike,
+		"active<1":                restful.OpLt,
+		"active=between:0..1":     restful.OpBetween,
+		"status~=op":              restful.OpLike,
+		"status=between:a..b":     restful.OpBetween,
+		"name=open|(age~=1,id=1)": restful.OpLike,
+	} {
+		_, _, err := restful.Prepare(cfg, restful.Request{Filter: filter})
+
+		var opErr *restful.OperatorError
+		if assert.True(t, errors.As(err, &opErr), filter) {
+			assert.Equal(t, op, opErr.Operator, filter)
+		}
+
+		assert.True(t, errors.Is(err, restful.ErrOperatorNotAllowed), filter)
+	}
+
+	_, _, err := restful.Count(cfg, restful.Request{Filter: "age~=1"})
+	assert.EqualError(t, err, `filter: the operator "~=" is not allowed for the field age ("age~=1" at 0)`)
+}
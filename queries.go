@@ -1,6 +1,7 @@
 package restful
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -12,10 +13,24 @@ var (
 	ErrFilterNotAllowed      = errors.New("the filter is not allowed")
 	ErrOrderInvalidStructure = errors.New("The order string does not match the allowed structure")
 	ErrOrderNotAllowed       = errors.New("The order is not allowed")
+	ErrFilterTooManyValues   = errors.New("the filter contains too many values")
+	ErrFilterMixedAggregate  = errors.New("the filter combines aggregated and plain fields within one condition")
+	ErrSearchTooManyTerms    = errors.New("the search contains too many terms")
+	ErrLimitExceeded         = errors.New("the limit exceeds the maximum page size")
 )
 
 const (
-	LimitDefault = 50
+	LimitDefault        = 50
+	FilterValuesDefault = 100
+	SearchTermsDefault  = 10
+)
+
+// Defines how limits above Config.MaxLimit are handled.
+type LimitPolicy int
+
+const (
+	LimitClamp  LimitPolicy = iota
+	LimitReject LimitPolicy = iota
 )
 
 type (
@@ -25,10 +40,50 @@ type (
 		Fields           Fields
 		Distinct         bool
 		Table            string
+		Joins            Joins
 		Where            string
 		GroupBy          string
 		CalcRows         bool
 		AdditionalParams Params
+
+		// The SQL dialect used to render the query, defaults to MySQL.
+		Dialect Dialect
+
+		// Maximum amount of values within a single IN filter, defaults to FilterValuesDefault.
+		MaxFilterValues int
+
+		// The unique field that completes the order for keyset pagination, see cursor.go.
+		// Cursors are signed with the secret, if one is given.
+		CursorKey    string
+		CursorSecret []byte
+
+		// The page size used when the request does not define a limit, defaults to LimitDefault.
+		// NoLimit returns all rows instead. Limits above MaxLimit (if set) are clamped or
+		// rejected, depending on the LimitPolicy.
+		DefaultLimit uint
+		MaxLimit     uint
+		LimitPolicy  LimitPolicy
+		NoLimit      bool
+
+		// Only fields marked as Filterable or Sortable can be used to filter or order.
+		Strict bool
+
+		// Maps the public field names to their columns, e.g. SnakeCase.
+		Naming NamingStrategy
+
+		// The related resources that can be expanded, see expand.go.
+		Relations Relations
+
+		// How the search is matched, see search.go. Full-text searches match the declared
+		// columns (which must equal a FULLTEXT index for MySQL) or the searchable fields.
+		// Columns of fields that the principal cannot access are left out. The relevance of the rows is exposed as a sortable pseudo-field, if named.
+		SearchMode     SearchMode
+		SearchColumns  []string
+		SearchLanguage string
+		Relevance      string
+
+		// Maximum amount of terms of a LIKE search, defaults to SearchTermsDefault.
+		MaxSearchTerms int
 	}
 
 	// Additional params that will be injected into the overall query building proces.
@@ -43,122 +98,200 @@ type (
 		Limit  uint   `json:"limit" form:"limit" query:"limit"`
 		Offset uint   `json:"offset" form:"offset" query:"offset"`
 		Search string `json:"search" form:"search" query:"search"`
+		Cursor string `json:"cursor" form:"cursor" query:"cursor"`
+		Expand string `json:"expand" form:"expand" query:"expand"`
 	}
 )
 
-func Prepare(cfg Config, req Request) (query string, args map[string]interface{}, err error) {
+// Returns the table and all joins.
+func (cfg Config) from() string {
+	if len(cfg.Joins) == 0 {
+		return cfg.Table
+	}
+
+	return cfg.Table + " " + cfg.Joins.String()
+}
 
-	args = map[string]interface{}{}
+// Returns the effective page size for the requested limit, 0 means no limit.
+func (cfg Config) limit(requested uint) (uint, error) {
 
-	// Add the fixed (or default) fields
-	if len(cfg.Fields) == 0 {
-		err = ErrNoFields
-		return
-	}
+	if requested == 0 {
+		if cfg.NoLimit {
+			return 0, nil
+		}
 
-	var fields Fields
-	if fields, err = selectFields(req.Fields, cfg.Fields); err != nil {
-		return
+		requested = cfg.DefaultLimit
+		if requested == 0 {
+			requested = LimitDefault
+		}
 	}
 
-	// Prepare the order
-	var order string
-	if order, err = prepareOrder(req.Order, cfg.Fields); err != nil {
-		return
+	if cfg.MaxLimit > 0 && requested > cfg.MaxLimit {
+		if cfg.LimitPolicy == LimitReject {
+			return 0, ErrLimitExceeded
+		}
+
+		return cfg.MaxLimit, nil
 	}
 
-	var filter string
+	return requested, nil
+}
 
-	if filter, err = prepareFilter(req.Filter, &args, cfg.Fields); err != nil {
-		return
+// Returns the configured dialect or the default one.
+func (cfg Config) dialect() Dialect {
+	if cfg.Dialect == nil {
+		return MySQL
 	}
 
-	var search string
-	if search, err = prepareSearch(cfg.Fields, &args, req.Search); err != nil {
-		return
+	return cfg.Dialect
+}
+
+func Prepare(cfg Config, req Request) (query string, args map[string]interface{}, err error) {
+	return PrepareContext(context.Background(), cfg, req)
+}
+
+// PrepareContext works like Prepare, but only allows the fields that the principal of the
+// context can access.
+func PrepareContext(ctx context.Context, cfg Config, req Request) (query string, args map[string]interface{}, err error) {
+	return prepare(cfg.restrict(PrincipalFrom(ctx)), req, false)
+}
+
+// Builds and renders the query.
+func prepare(cfg Config, req Request, counting bool) (query string, args map[string]interface{}, err error) {
+	q, err := build(cfg, req, counting)
+	if err != nil {
+		return "", nil, err
 	}
 
-	// Merge the filter params and the custom ones
-	if cfg.AdditionalParams != nil {
-		for k, v := range cfg.AdditionalParams {
-			args[k] = v
-		}
+	return q.SQL(), q.Args, nil
+}
+
+// The actual query builder. Counting queries skip the order and the pagination, as some systems
+// (e.g. SQL Server) do not allow an ORDER BY within a derived table.
+func build(cfg Config, req Request, counting bool) (*Query, error) {
+
+	args := map[string]interface{}{}
+	cfg = cfg.named().withRelevance(req.Search)
+
+	// Add the fixed (or default) fields
+	if len(cfg.Fields) == 0 {
+		return nil, ErrNoFields
 	}
 
-	// Build the query
-	query = "SELECT"
+	fields, err := selectFields(req.Fields, cfg.Fields)
+	if err != nil {
+		return nil, asQueryError("fields", req.Fields, err)
+	}
 
-	if cfg.Distinct {
-		query += " DISTINCT"
+	if !counting {
+		if fields, err = selectExpandKeys(cfg, req.Expand, fields); err != nil {
+			return nil, asQueryError("expand", req.Expand, err)
+		}
 	}
 
-	if cfg.CalcRows {
-		query += " SQL_CALC_FOUND_ROWS"
+	// Prepare the order
+	var order orderBy
+	if !counting {
+		if order, err = prepareOrder(req.Order, cfg); err != nil {
+			return nil, asQueryError("order", req.Order, err)
+		}
 	}
 
-	// Join the field configuration together and add to the query string.
-	var fieldStr = fields[0].String()
-	for _, f := range fields[1:] {
-		fieldStr = fieldStr + ", " + f.String()
+	filter, having, err := prepareFilter(req.Filter, &args, cfg)
+	if err != nil {
+		return nil, asQueryError("filter", req.Filter, err)
 	}
 
-	query = fmt.Sprintf("%s %s FROM %s", query, fieldStr, cfg.Table)
+	// Keyset pagination replaces the offset by a seek condition
+	if !counting && len(req.Cursor) > 0 {
+		var seek string
+		var aggregate bool
 
-	//
-	// WHERE
-	//
+		if order, seek, aggregate, err = prepareCursor(cfg, order, req.Cursor, &args); err != nil {
+			return nil, asQueryError("cursor", req.Cursor, err)
+		}
 
-	requirements := []string{}
-	if len(cfg.Where) > 0 {
-		requirements = append(requirements, cfg.Where)
-	}
+		if aggregate {
+			having = andCondition(having, Raw(seek))
+		} else {
+			filter = andCondition(filter, Raw(seek))
+		}
 
-	if len(filter) > 0 {
-		requirements = append(requirements, filter)
+		req.Offset = 0
 	}
 
-	if len(search) > 0 {
-		requirements = append(requirements, search)
+	search, err := prepareSearch(cfg, &args, req.Search)
+	if err != nil {
+		return nil, asQueryError("search", req.Search, err)
 	}
 
-	if len(requirements) > 0 {
-		query += " WHERE " + strings.Join(requirements, " AND ")
+	// Merge the filter params and the custom ones
+	if cfg.AdditionalParams != nil {
+		for k, v := range cfg.AdditionalParams {
+			args[k] = v
+		}
 	}
 
-	//
-	// GROUP
-	//
+	q := &Query{
+		Distinct: cfg.Distinct,
+		CalcRows: cfg.CalcRows,
+		Select:   make([]Column, len(fields)),
+		From:     cfg.from(),
+		Where:    cfg.where(filter, search),
+		GroupBy:  cfg.GroupBy,
+		Having:   having,
+		OrderBy:  order.sorts(),
+		Args:     args,
+		Dialect:  cfg.dialect(),
+	}
 
-	if len(cfg.GroupBy) > 0 {
-		query += fmt.Sprintf(" GROUP BY %s", cfg.GroupBy)
+	for i, f := range fields {
+		q.Select[i] = f.column()
 	}
 
-	if len(order) != 0 {
-		query += " ORDER BY " + order
+	if counting {
+		return q, nil
 	}
 
-	if req.Limit < 0 {
-		req.Limit = LimitDefault
+	if q.Limit, err = cfg.limit(req.Limit); err != nil {
+		return nil, asQueryError("limit", fmt.Sprint(req.Limit), err)
 	}
 
-	if req.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d", req.Limit)
+	q.Offset = req.Offset
+
+	return q, nil
+}
+
+// Returns the WHERE tree of the fixed condition, the filter and the search. The fixed condition
+// is grouped, as it might contain an OR.
+func (cfg Config) where(filter Condition, search string) Condition {
+	where := And()
+
+	if len(cfg.Where) > 0 {
+		where = andCondition(where, Group(Raw(cfg.Where)))
 	}
 
-	if req.Offset > 0 {
-		query += fmt.Sprintf(" OFFSET %d", req.Offset)
+	where.Children = append(where.Children, filter.Children...)
+
+	if len(search) > 0 {
+		where = andCondition(where, Raw(search))
 	}
 
-	return query, args, nil
+	return where
 }
 
 func Count(cfg Config, req Request) (query string, args map[string]interface{}, err error) {
+	return CountContext(context.Background(), cfg, req)
+}
+
+// CountContext works like Count, but only allows the fields that the principal of the context
+// can access.
+func CountContext(ctx context.Context, cfg Config, req Request) (query string, args map[string]interface{}, err error) {
 
-	req.Offset = 0
-	req.Limit = 0
 	req.Order = ""
+	req.Cursor = ""
 
-	query, args, err = Prepare(cfg, req)
+	query, args, err = prepare(cfg.restrict(PrincipalFrom(ctx)), req, true)
 	if err != nil {
 		return
 	}
@@ -172,21 +305,35 @@ func selectFields(raw string, fields Fields) (Fields, error) {
 
 	selection := make(Fields, 0, len(fields))
 
+	// Hidden and inaccessible fields are never selected
 	if raw == "" {
-		return fields, nil
+		for _, v := range fields {
+			if !v.IsHidden && !v.forbidden {
+				selection = append(selection, v)
+			}
+		}
+
+		if len(selection) == 0 {
+			return nil, ErrNoFields
+		}
+
+		return selection, nil
 	}
 
 	// Make sure to add all required fields
 	for _, v := range fields {
-		if v.IsRequired {
+		if v.IsRequired && !v.IsHidden && !v.forbidden {
 			selection = append(selection, v)
 		}
 	}
 
 	parts := strings.Split(raw, ",")
+	pos := 0
 
 partsLoop:
 	for _, part := range parts {
+		start := pos
+		pos += len([]rune(part)) + 1
 
 		// Skip anything that contains false data. We do not throw errors
 		// as it makes it easier to have some custom field types, that must be extended manually.
@@ -195,11 +342,15 @@ partsLoop:
 		}
 
 		for _, f := range fields {
-			if part == f.Name {
+			if part == f.Name && !f.IsHidden {
+
+				if f.forbidden {
+					return nil, newQueryError("fields", part, start, ErrFieldForbidden)
+				}
 
 				// Make sure it is not twice in there
 				for _, s := range selection {
-					if s == f {
+					if s.Name == f.Name {
 						continue partsLoop
 					}
 				}
@@ -231,162 +382,92 @@ func prependTableName(fields *[]string, table string) {
 	}
 }
 
-// Takes in a param filter string and creates a sql appropriate representation. Also
-// ensures that only parameters are used that
-func prepareFilter(filter string, args *map[string]interface{}, valid Fields) (string, error) {
-
-	if filter == "" {
-		return "", nil
-	}
-
-	parts := strings.Split(filter, ",")
-	sql := make([]string, 0, len(parts))
-
-	for i, part := range parts {
-
-		// rgx.MatchString(part)
-		matches := filterRegex.FindStringSubmatch(part)
-
-		if len(matches) != 4 {
-			return "", ErrFilterStructure
-		}
-
-		// make sure that the given parameter is part of the valid list
-		param, cmp, value := matches[1], matches[2], matches[3]
-
-		isValid := false
-		for _, v := range valid {
-			if param == v.Name {
-				isValid = true
-				break
-			}
-		}
-
-		if !isValid {
-			return "", ErrFilterNotAllowed
-		}
-
-		// Prepare the SQL string
-		key := fmt.Sprintf("%s%d", param, i)
-
-		if cmp != "~=" {
-			sql = append(sql, fmt.Sprintf("%s %s :%s", param, cmp, key))
-			(*args)[key] = value
-		} else {
-			// Prepare the search parameters by adding an additional parameter
+type (
+	// The order of a query
+	orderBy []orderTerm
 
-			sql = append(sql, fmt.Sprintf("%s LIKE :%s", param, key))
-			search := strings.Replace(value, "*", "%", -1)
-			(*args)[key] = "%" + search + "%"
-		}
+	orderTerm struct {
+		field     field
+		direction OrderType
 	}
+)
 
-	return strings.Join(sql, " AND "), nil
-}
-
-func prepareOrder(raw string, fields Fields) (string, error) {
+func prepareOrder(raw string, cfg Config) (orderBy, error) {
 
 	if raw == "" {
-		return generateDefaultOrder(fields), nil
+		return generateDefaultOrder(cfg.Fields), nil
 	}
 
 	parts := strings.Split(raw, ",")
-	order := make([]string, 0, len(parts))
+	order := make(orderBy, 0, len(parts))
+	pos := 0
 
 	for _, part := range parts {
+		start := pos
+		pos += len([]rune(part)) + 1
 
 		matches := orderRegex.FindStringSubmatch(part)
 
 		// Important! Remember that the first result is always the full match
 		if len(matches) != 3 {
-			return "", ErrOrderInvalidStructure
+			return nil, newQueryError("order", part, start, ErrOrderInvalidStructure)
 		}
 
 		// Make sure that the given parameter is part of the valid list and that the field exists.
 		mark, param := matches[1], matches[2]
 
-		isValid := false
-		for _, v := range fields {
-			if param == v.Name {
-				isValid = true
-				break
-			}
+		f, isValid := cfg.Fields.find(param)
+		if !isValid || !f.canSort(cfg.Strict) {
+			return nil, newQueryError("order", part, start, ErrOrderNotAllowed)
 		}
 
-		if !isValid {
-			return "", ErrOrderNotAllowed
+		if f.forbidden {
+			return nil, newQueryError("order", part, start, ErrFieldForbidden)
 		}
 
-		// Prepare the SQL string
-		key := "ASC"
+		direction := ASC
 		if mark == "-" {
-			key = "DESC"
+			direction = DESC
 		}
 
-		order = append(order, fmt.Sprintf("%s %s", param, key))
+		order = append(order, orderTerm{field: f, direction: direction})
 	}
 
-	return strings.Join(order, ", "), nil
+	return order, nil
 }
 
-// Generate the default order based on the given fields
-func generateDefaultOrder(fields Fields) string {
-	if len(fields) == 0 {
-		return ""
-	}
-
-	var out []string
+// Generate the default order based on the given (accessible) fields
+func generateDefaultOrder(fields Fields) orderBy {
+	var out orderBy
 
 	for _, f := range fields {
-
-		switch f.Order {
-		case OrderNone:
-			continue
-		case ASC:
-			out = append(out, fmt.Sprint(f.Name, " ASC"))
-			break
-		case DESC:
-			out = append(out, fmt.Sprint(f.Name, " DESC"))
-			break
+		if f.Order != OrderNone && !f.forbidden {
+			out = append(out, orderTerm{field: f, direction: f.Order})
 		}
-
 	}
 
-	return strings.Join(out, ", ")
+	return out
 }
 
-func prepareSearch(fields Fields, args *map[string]interface{}, req string) (string, error) {
-
-	if len(req) == 0 {
-		return "", nil
-	}
-
-	parts := make([]string, 0, len(fields))
-
-	// The search request is alwasys transformed into a string, therefore there should not be
-	// a problem with injections.
-	key := "__restful_search"
-	search := strings.Replace(req, "*", "%", -1)
-	(*args)[key] = "%" + search + "%"
-
-	// Find all fields that are searchable
-	for _, f := range fields {
-		if !f.IsSearchable {
-			continue
+// Returns the order with all directions inverted
+func (o orderBy) reverse() orderBy {
+	out := make(orderBy, len(o))
+	for i, t := range o {
+		out[i] = t
+		out[i].direction = ASC
+		if t.direction == ASC {
+			out[i].direction = DESC
 		}
-
-		param := f.Name
-		parts = append(parts, fmt.Sprintf("%s LIKE :%s", param, key))
 	}
 
-	if len(parts) == 0 {
-		return "", nil
-	}
+	return out
+}
 
-	out := strings.Join(parts, " OR ")
-	if len(parts) == 1 {
-		return out, nil
+func (o orderBy) sorts() []Sort {
+	sorts := make([]Sort, len(o))
+	for i, t := range o {
+		sorts[i] = Sort{Expr: t.field.sortExpr(), Direction: t.direction}
 	}
 
-	return fmt.Sprintf("(%s)", out), nil
+	return sorts
 }
@@ -1,27 +1,12 @@
 package restful_test
 
 import (
+	"errors"
 	"github.com/joernlenoch/go-restful"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
 
-/*
-TODO
-
-func TestBuilder(t *testing.T) {
-  restful.Select().Distinct()
-    .Fields(
-      restful.Field("company_id").Required(),
-      restful.Field("name"),
-      restful.Field("roles").QueryBy(`CONCAT("[", GROUP_CONCAT(JSON_QUOTE(role)),"]")`),
-    )
-    .Where("").And("").Or("")
-    .From("user_company").Join("company").Using("company_id")
-    .GroupBy("company_id")
-}
-*/
-
 func TestPrepare_OptionalFields(t *testing.T) {
 	t.Parallel()
 
@@ -36,7 +21,7 @@ func TestPrepare_OptionalFields(t *testing.T) {
 	})
 
 	assert.NoError(t, err, "must not throw errors")
-	assert.Equal(t, "SELECT name FROM user", query)
+	assert.Equal(t, "SELECT name FROM user LIMIT 50", query)
 	assert.Empty(t, args, "should not have arguments")
 }
 
@@ -52,7 +37,7 @@ func TestPrepare_Fields(t *testing.T) {
 	}, restful.Request{})
 
 	assert.NoError(t, err, "must not throw errors")
-	assert.Equal(t, "SELECT name, age FROM user", query)
+	assert.Equal(t, "SELECT name, age FROM user LIMIT 50", query)
 	assert.Empty(t, args, "should not have arguments")
 }
 
@@ -86,7 +71,7 @@ func TestPrepare_AltQuery(t *testing.T) {
 	}, restful.Request{})
 
 	assert.NoError(t, err, "must not throw errors")
-	assert.Equal(t, "SELECT user.name AS 'name', age FROM `user`", query)
+	assert.Equal(t, "SELECT user.name AS 'name', age FROM `user` LIMIT 50", query)
 	assert.Empty(t, args, "should not have arguments")
 }
 
@@ -104,9 +89,9 @@ func TestPrepare_FilterSearch(t *testing.T) {
 	})
 
 	assert.NoError(t, err, "must not throw errors")
-	assert.Equal(t, "SELECT name, age FROM user WHERE name LIKE :name0", query)
+	assert.Equal(t, "SELECT name, age FROM user WHERE name LIKE :__restful_filter0 ESCAPE '!' LIMIT 50", query)
 	assert.Equal(t, 1, len(args), "should have 1 arguments")
-	assert.Equal(t, "%a%sd%", args["name0"], "should have transformed args")
+	assert.Equal(t, "%a%sd%", args["__restful_filter0"], "should have transformed args")
 }
 
 func TestPrepare_Fields_Error(t *testing.T) {
@@ -158,7 +143,7 @@ func TestPrepare_Injection(t *testing.T) {
 	})
 
 	assert.NoError(t, err, "must not throw errors")
-	assert.Equal(t, "SELECT name, identifier FROM user WHERE (name LIKE :__restful_search OR identifier LIKE :__restful_search)", query)
+	assert.Equal(t, "SELECT name, identifier FROM user WHERE (name LIKE :__restful_search ESCAPE '!' OR identifier LIKE :__restful_search ESCAPE '!') LIMIT 50", query)
 	assert.Equal(t, "%hallo%test%", args["__restful_search"])
 
 	//
@@ -177,7 +162,7 @@ func TestPrepare_Injection(t *testing.T) {
 	})
 
 	assert.NoError(t, err, "must not throw errors")
-	assert.Equal(t, "SELECT name FROM user WHERE (name LIKE :__restful_search OR identifier LIKE :__restful_search)", query)
+	assert.Equal(t, "SELECT name FROM user WHERE (name LIKE :__restful_search ESCAPE '!' OR identifier LIKE :__restful_search ESCAPE '!') LIMIT 50", query)
 	assert.Equal(t, "%hallo%test%", args["__restful_search"])
 }
 
@@ -216,5 +201,159 @@ func TestCount_WithFilter(t *testing.T) {
 	})
 
 	assert.NoError(t, err, "must not throw errors")
-	assert.Equal(t, "SELECT COUNT(*) FROM (SELECT name, age FROM user WHERE age = :age0 AND name LIKE :__restful_search) t", query)
+	assert.Equal(t, "SELECT COUNT(*) FROM (SELECT name, JSON_QUERY(age) AS 'age' FROM user WHERE JSON_QUERY(age) = :__restful_filter0 AND name LIKE :__restful_search ESCAPE '!') t", query)
+}
+
+func TestPrepare_QueryExpressions(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("name").QueryBy("LOWER(u.name)"),
+			restful.Field("age").QueryBy("JSON_VALUE(u.data, '$.age')").FilterBy("age").SortBy("age"),
+		},
+		Table: "user u",
+	}
+
+	query, _, err := restful.Prepare(cfg, restful.Request{
+		Filter: "name=a,age>3",
+		Order:  "name,-age",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT LOWER(u.name) AS 'name', JSON_VALUE(u.data, '$.age') AS 'age' FROM user u "+
+		"WHERE LOWER(u.name) = :__restful_filter0 AND age > :__restful_filter1 ORDER BY LOWER(u.name) ASC, age DESC LIMIT 50", query)
+}
+
+func TestPrepare_Having(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id").QueryBy("u.id"),
+			restful.Field("orders").QueryBy("COUNT(o.id)"),
+			restful.Field("latest").QueryBy("(SELECT MAX(created) FROM login l WHERE l.user_id = u.id)"),
+		},
+		Table:   "user u",
+		Joins:   restful.Joins{restful.LeftJoin("orders", "o").On("o.user_id = u.id")},
+		GroupBy: "u.id",
+	}
+
+	query, args, err := restful.Prepare(cfg, restful.Request{
+		Fields: "id",
+		Filter: "orders>5,id=in:(1|2),orders<10|orders=0",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT u.id AS 'id' FROM user u LEFT JOIN orders o ON o.user_id = u.id "+
+		"WHERE u.id IN (:__restful_filter1_0, :__restful_filter1_1) GROUP BY u.id HAVING COUNT(o.id) > :__restful_filter0 AND "+
+		"(COUNT(o.id) < :__restful_filter2 OR COUNT(o.id) = :__restful_filter3) LIMIT 50", query)
+	assert.Len(t, args, 5)
+
+	// Sub queries are not aggregated
+	query, _, err = restful.Prepare(cfg, restful.Request{
+		Fields: "id",
+		Filter: "latest=x",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT u.id AS 'id' FROM user u LEFT JOIN orders o ON o.user_id = u.id "+
+		"WHERE (SELECT MAX(created) FROM login l WHERE l.user_id = u.id) = :__restful_filter0 GROUP BY u.id LIMIT 50", query)
+
+	_, _, err = restful.Prepare(cfg, restful.Request{Filter: "orders>5|id=1"})
+	assert.True(t, errors.Is(err, restful.ErrFilterMixedAggregate))
+}
+
+func TestPrepare_Limit(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields:       restful.Fields{restful.Field("name")},
+		Table:        "user",
+		DefaultLimit: 20,
+		MaxLimit:     100,
+	}
+
+	query, _, err := restful.Prepare(cfg, restful.Request{})
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name FROM user LIMIT 20", query)
+
+	query, _, err = restful.Prepare(cfg, restful.Request{Limit: 500, Offset: 10})
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name FROM user LIMIT 100 OFFSET 10", query, "must clamp the limit")
+
+	cfg.LimitPolicy = restful.LimitReject
+
+	query, _, err = restful.Prepare(cfg, restful.Request{Limit: 500})
+	assert.True(t, errors.Is(err, restful.ErrLimitExceeded))
+	assert.Empty(t, query, "should not return a query")
+
+	cfg.NoLimit = true
+
+	query, _, err = restful.Prepare(cfg, restful.Request{})
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name FROM user", query, "must not limit the query")
+
+	query, _, err = restful.Count(cfg, restful.Request{Limit: 500})
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT COUNT(*) FROM (SELECT name FROM user) t", query, "must not limit the count")
+}
+
+func TestPrepare_Capabilities(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("name").Required(),
+			restful.Field("password_hash").Hidden().Required(),
+			restful.Field("tenant").Hidden().Filterable(),
+			restful.Field("internal_score").Hidden().Sortable().Searchable(),
+		},
+		Table: "user",
+	}
+
+	query, _, err := restful.Prepare(cfg, restful.Request{
+		Fields: "name,password_hash,tenant",
+		Filter: "tenant=4",
+		Order:  "-internal_score",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name FROM user WHERE tenant = :__restful_filter0 ORDER BY internal_score DESC LIMIT 50", query)
+
+	query, _, err = restful.Prepare(cfg, restful.Request{})
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name FROM user LIMIT 50", query, "must never select hidden fields")
+
+	_, _, err = restful.Prepare(cfg, restful.Request{Filter: "password_hash=x"})
+	assert.True(t, errors.Is(err, restful.ErrFilterNotAllowed))
+
+	_, _, err = restful.Prepare(cfg, restful.Request{Order: "password_hash"})
+	assert.True(t, errors.Is(err, restful.ErrOrderNotAllowed))
+
+	_, _, err = restful.Prepare(cfg, restful.Request{Fields: "password_hash,internal_score"})
+	assert.NoError(t, err, "required fields remain selected")
+}
+
+func TestPrepare_Strict(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("name").Filterable(),
+			restful.Field("age").Sortable(),
+		},
+		Table:  "user",
+		Strict: true,
+	}
+
+	query, _, err := restful.Prepare(cfg, restful.Request{Filter: "name=a", Order: "age"})
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT name, age FROM user WHERE name = :__restful_filter0 ORDER BY age ASC LIMIT 50", query)
+
+	_, _, err = restful.Prepare(cfg, restful.Request{Filter: "age=1"})
+	assert.True(t, errors.Is(err, restful.ErrFilterNotAllowed))
+
+	_, _, err = restful.Prepare(cfg, restful.Request{Order: "name"})
+	assert.True(t, errors.Is(err, restful.ErrOrderNotAllowed))
 }
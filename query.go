@@ -0,0 +1,260 @@
+package restful
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type (
+
+	// Query is the prepared, but not yet rendered query. It can be inspected and modified, e.g.
+	// by a middleware that adds a predicate, before it is rendered with SQL.
+	Query struct {
+		Distinct bool
+		CalcRows bool
+		Select   []Column
+		From     string
+		Where    Condition
+		GroupBy  string
+		Having   Condition
+		OrderBy  []Sort
+		Limit    uint
+		Offset   uint
+
+		// The named arguments of all conditions
+		Args map[string]interface{}
+
+		// The dialect used to render the query, defaults to MySQL.
+		Dialect Dialect
+	}
+
+	// An entry of the select list. Columns with an expression are aliased by their name.
+	Column struct {
+		Name string
+		Expr string
+	}
+
+	// Condition is a node of a WHERE or HAVING tree. It either combines its children with
+	// AND, OR or NOT, wraps them in parenthesis (Group) or is a plain SQL predicate.
+	Condition struct {
+		Op       string
+		Children []Condition
+		SQL      string
+	}
+
+	// An entry of the ORDER BY clause.
+	Sort struct {
+		Expr      string
+		Direction OrderType
+	}
+)
+
+// Raw returns a plain SQL predicate. Arguments of the predicate must be added to Query.Args.
+func Raw(sql string) Condition {
+	return Condition{SQL: sql}
+}
+
+func And(children ...Condition) Condition {
+	return Condition{Op: "AND", Children: children}
+}
+
+func Or(children ...Condition) Condition {
+	return Condition{Op: "OR", Children: children}
+}
+
+func Not(child Condition) Condition {
+	return Condition{Op: "NOT", Children: []Condition{child}}
+}
+
+// Group wraps the condition in parenthesis, e.g. a raw predicate that contains an OR.
+func Group(child Condition) Condition {
+	return Condition{Op: "GROUP", Children: []Condition{child}}
+}
+
+// Tells if the condition is empty and therefore not rendered at all. This includes logical
+// conditions without any non empty child.
+func (c Condition) IsEmpty() bool {
+	if len(c.Op) == 0 {
+		return len(c.SQL) == 0
+	}
+
+	for _, child := range c.Children {
+		if !child.IsEmpty() {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c Condition) String() string {
+	switch c.Op {
+	case "":
+		return c.SQL
+	case "GROUP":
+		return "(" + c.Children[0].String() + ")"
+	case "NOT":
+		if len(c.Children[0].Op) > 0 {
+			return "NOT " + c.Children[0].String()
+		}
+
+		return fmt.Sprintf("NOT (%s)", c.Children[0])
+	}
+
+	return "(" + c.join() + ")"
+}
+
+// Joins the non empty children with the operator.
+func (c Condition) join() string {
+	parts := make([]string, 0, len(c.Children))
+	for _, child := range c.Children {
+		if !child.IsEmpty() {
+			parts = append(parts, child.String())
+		}
+	}
+
+	return strings.Join(parts, " "+c.Op+" ")
+}
+
+// Renders the condition as the top level of a clause. The AND of the top level needs no
+// parenthesis, neither does a single group.
+func (c Condition) clause() string {
+	if c.IsEmpty() {
+		return ""
+	}
+
+	switch c.Op {
+	case "AND":
+		parts := make([]Condition, 0, len(c.Children))
+		for _, child := range c.Children {
+			if !child.IsEmpty() {
+				parts = append(parts, child)
+			}
+		}
+
+		if len(parts) == 1 {
+			return parts[0].clause()
+		}
+
+		return Condition{Op: "AND", Children: parts}.join()
+	case "GROUP":
+		return c.Children[0].String()
+	}
+
+	return c.String()
+}
+
+// AddWhere adds the condition with AND to the WHERE clause.
+func (q *Query) AddWhere(c Condition) {
+	q.Where = andCondition(q.Where, c)
+}
+
+// AddHaving adds the condition with AND to the HAVING clause.
+func (q *Query) AddHaving(c Condition) {
+	q.Having = andCondition(q.Having, c)
+}
+
+// Appends the condition to the top level AND of the tree.
+func andCondition(tree Condition, c Condition) Condition {
+	if tree.IsEmpty() {
+		return And(c)
+	}
+
+	if tree.Op != "AND" {
+		tree = And(tree)
+	}
+
+	children := make([]Condition, len(tree.Children), len(tree.Children)+1)
+	copy(children, tree.Children)
+
+	tree.Children = append(children, c)
+	return tree
+}
+
+func (q *Query) dialect() Dialect {
+	if q.Dialect == nil {
+		return MySQL
+	}
+
+	return q.Dialect
+}
+
+// SQL renders the query.
+func (q *Query) SQL() string {
+	dialect := q.dialect()
+
+	query := "SELECT"
+
+	if q.Distinct {
+		query += " DISTINCT"
+	}
+
+	if q.CalcRows {
+		if mod := dialect.FoundRows(); len(mod) > 0 {
+			query += " " + mod
+		}
+	}
+
+	columns := make([]string, len(q.Select))
+	for i, c := range q.Select {
+		columns[i] = c.render(dialect)
+	}
+
+	query = fmt.Sprintf("%s %s FROM %s", query, strings.Join(columns, ", "), q.From)
+
+	if where := q.Where.clause(); len(where) > 0 {
+		query += " WHERE " + where
+	}
+
+	if len(q.GroupBy) > 0 {
+		query += fmt.Sprintf(" GROUP BY %s", q.GroupBy)
+	}
+
+	if having := q.Having.clause(); len(having) > 0 {
+		query += " HAVING " + having
+	}
+
+	if len(q.OrderBy) > 0 {
+		parts := make([]string, len(q.OrderBy))
+		for i, s := range q.OrderBy {
+			parts[i] = fmt.Sprintf("%s %s", s.Expr, s.Direction)
+		}
+
+		query += " ORDER BY " + strings.Join(parts, ", ")
+	}
+
+	if page := dialect.Paginate(q.Limit, q.Offset, len(q.OrderBy) > 0); len(page) > 0 {
+		query += " " + page
+	}
+
+	return query
+}
+
+func (c Column) render(d Dialect) string {
+	if len(c.Expr) > 0 {
+		return fmt.Sprintf("%s AS %s", c.Expr, d.Alias(c.Name))
+	}
+
+	return c.Name
+}
+
+// Returns the select list entry of the field.
+func (f field) column() Column {
+	if len(f.Query) > 0 {
+		return Column{Name: f.Name, Expr: f.expr()}
+	}
+
+	return Column{Name: f.Name}
+}
+
+// Build creates the query of the request, without rendering it. See Prepare.
+func Build(cfg Config, req Request) (*Query, error) {
+	return BuildContext(context.Background(), cfg, req)
+}
+
+// BuildContext works like Build, but only allows the fields that the principal of the context
+// can access.
+func BuildContext(ctx context.Context, cfg Config, req Request) (*Query, error) {
+	return build(cfg.restrict(PrincipalFrom(ctx)), req, false)
+}
@@ -0,0 +1,121 @@
+package restful_test
+
+import (
+	"github.com/joernlenoch/go-restful"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBuild(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id"),
+			restful.Field("name").Searchable(),
+			restful.Field("company").QueryBy("c.name"),
+			restful.Field("orders").QueryBy("COUNT(o.id)"),
+		},
+		Table:   "user u",
+		Joins:   restful.Joins{restful.InnerJoin("company", "c").On("c.id = u.company_id")},
+		Where:   "u.deleted = 0 OR u.admin = 1",
+		GroupBy: "u.id",
+	}
+
+	req := restful.Request{Filter: "name=anna|name=bob,orders>3", Order: "-name", Search: "an", Limit: 10}
+
+	q, err := restful.Build(cfg, req)
+	assert.NoError(t, err, "must not throw errors")
+
+	assert.Equal(t, []restful.Column{
+		{Name: "id"},
+		{Name: "name"},
+		{Name: "company", Expr: "c.name"},
+		{Name: "orders", Expr: "COUNT(o.id)"},
+	}, q.Select)
+	assert.Equal(t, "user u INNER JOIN company c ON c.id = u.company_id", q.From)
+	assert.Equal(t, restful.And(
+		restful.Group(restful.Raw("u.deleted = 0 OR u.admin = 1")),
+		restful.Or(restful.Raw("name = :__restful_filter0"), restful.Raw("name = :__restful_filter1")),
+		restful.Raw("name LIKE :__restful_search ESCAPE '!'"),
+	), q.Where)
+	assert.Equal(t, restful.And(restful.Raw("COUNT(o.id) > :__restful_filter2")), q.Having)
+	assert.Equal(t, []restful.Sort{{Expr: "name", Direction: restful.DESC}}, q.OrderBy)
+	assert.Equal(t, uint(10), q.Limit)
+	assert.Equal(t, "anna", q.Args["__restful_filter0"])
+
+	// Prepare renders the very same query
+	query, args, err := restful.Prepare(cfg, req)
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, query, q.SQL())
+	assert.Equal(t, args, q.Args)
+}
+
+func TestBuild_Modify(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id"),
+			restful.Field("name").Searchable(),
+			restful.Field("company").QueryBy("c.name"),
+			restful.Field("orders").QueryBy("COUNT(o.id)"),
+		},
+		Table:   "user u",
+		Joins:   restful.Joins{restful.InnerJoin("company", "c").On("c.id = u.company_id")},
+		Where:   "u.deleted = 0 OR u.admin = 1",
+		GroupBy: "u.id",
+	}
+
+	q, err := restful.Build(cfg, restful.Request{Fields: "id"})
+	assert.NoError(t, err, "must not throw errors")
+
+	assert.Equal(t, "SELECT id FROM user u INNER JOIN company c ON c.id = u.company_id "+
+		"WHERE u.deleted = 0 OR u.admin = 1 GROUP BY u.id LIMIT 50", q.SQL())
+
+	q.AddWhere(restful.Not(restful.Or(restful.Raw("c.id = :tenant"), restful.Raw("c.id IS NULL"))))
+	q.AddHaving(restful.Raw("COUNT(o.id) > 0"))
+	q.Args["tenant"] = 4
+	q.OrderBy = append(q.OrderBy, restful.Sort{Expr: "u.id", Direction: restful.ASC})
+	q.Limit, q.Offset = 5, 10
+
+	assert.Equal(t, "SELECT id FROM user u INNER JOIN company c ON c.id = u.company_id "+
+		"WHERE (u.deleted = 0 OR u.admin = 1) AND NOT (c.id = :tenant OR c.id IS NULL) GROUP BY u.id "+
+		"HAVING COUNT(o.id) > 0 ORDER BY u.id ASC LIMIT 5 OFFSET 10", q.SQL())
+
+	q.Dialect = restful.SQLServer
+	assert.Equal(t, "SELECT id FROM user u INNER JOIN company c ON c.id = u.company_id "+
+		"WHERE (u.deleted = 0 OR u.admin = 1) AND NOT (c.id = :tenant OR c.id IS NULL) GROUP BY u.id "+
+		"HAVING COUNT(o.id) > 0 ORDER BY u.id ASC OFFSET 10 ROWS FETCH NEXT 5 ROWS ONLY", q.SQL())
+
+	// Empty conditions are not rendered at all
+	q, err = restful.Build(cfg, restful.Request{Fields: "id"})
+	assert.NoError(t, err, "must not throw errors")
+
+	q.Where = restful.And()
+	q.AddWhere(restful.And())
+	q.AddHaving(restful.Or(restful.Raw("COUNT(o.id) > 0"), restful.And()))
+
+	assert.Equal(t, "SELECT id FROM user u INNER JOIN company c ON c.id = u.company_id "+
+		"GROUP BY u.id HAVING (COUNT(o.id) > 0) LIMIT 50", q.SQL())
+}
+
+func TestCondition_String(t *testing.T) {
+	t.Parallel()
+
+	a, b, c := restful.Raw("a = 1"), restful.Raw("b = 2"), restful.Raw("c = 3")
+
+	assert.Equal(t, "(a = 1 AND (b = 2 OR c = 3))", restful.And(a, restful.Or(b, c)).String())
+	assert.Equal(t, "NOT (a = 1)", restful.Not(a).String())
+	assert.Equal(t, "NOT (a = 1 OR b = 2)", restful.Not(restful.Or(a, b)).String())
+	assert.Equal(t, "(a = 1)", restful.Group(a).String())
+	assert.True(t, restful.Raw("").IsEmpty())
+
+	// Logical conditions without any non empty child are empty as well
+	assert.True(t, restful.And().IsEmpty())
+	assert.True(t, restful.Or(restful.And(), restful.Raw("")).IsEmpty())
+	assert.True(t, restful.Not(restful.Group(restful.Or())).IsEmpty())
+	assert.False(t, restful.Or(a, restful.And()).IsEmpty())
+	assert.Equal(t, "(a = 1)", restful.Or(a, restful.And()).String())
+	assert.Equal(t, "(a = 1 OR b = 2)", restful.Or(a, restful.Not(restful.And()), b).String())
+}
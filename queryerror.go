@@ -0,0 +1,112 @@
+package restful
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// The stable message keys of the query errors, by their sentinel.
+var queryErrorKeys = []struct {
+	err error
+	key string
+}{
+	{ErrNoFields, "no-fields"},
+	{ErrFieldForbidden, "field-forbidden"},
+	{ErrFilterStructure, "invalid-filter"},
+	{ErrFilterNotAllowed, "filter-not-allowed"},
+	{ErrFilterTooManyValues, "too-many-filter-values"},
+	{ErrFilterMixedAggregate, "mixed-aggregate-filter"},
+	{ErrSearchTooManyTerms, "too-many-search-terms"},
+	{ErrOperatorNotAllowed, "operator-not-allowed"},
+	{ErrInvalidValue, "invalid-value"},
+	{ErrOrderInvalidStructure, "invalid-order"},
+	{ErrOrderNotAllowed, "order-not-allowed"},
+	{ErrLimitExceeded, "limit-exceeded"},
+	{ErrCursorInvalid, "invalid-cursor"},
+	{ErrCursorNotSupported, "cursor-not-supported"},
+	{ErrCursorValue, "invalid-cursor"},
+	{ErrExpandInvalidStructure, "invalid-expand"},
+	{ErrExpandNotAllowed, "expand-not-allowed"},
+}
+
+// QueryError tells which part of the request could not be turned into a query. It is a
+// Response with a 400 code and matches its cause with errors.Is, e.g. ErrFilterNotAllowed.
+type QueryError struct {
+	response
+
+	// The request parameter, e.g. "filter" or "order"
+	Param string
+
+	// The offending part of the parameter and its position (in runes)
+	Token string
+	Pos   int
+
+	Err error
+}
+
+func newQueryError(param, token string, pos int, err error) *QueryError {
+	key := MsgInvalidRequest
+	for _, k := range queryErrorKeys {
+		if errors.Is(err, k.err) {
+			key = k.key
+			break
+		}
+	}
+
+	return &QueryError{
+		response: response{
+			Code:    http.StatusBadRequest,
+			Message: key,
+			Reason:  err.Error(),
+			Source:  err,
+		},
+		Param: param,
+		Token: token,
+		Pos:   pos,
+		Err:   err,
+	}
+}
+
+// Returns the error as a QueryError of the parameter. Errors without a more precise token
+// refer to the whole parameter.
+func asQueryError(param, raw string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var qe *QueryError
+	if errors.As(err, &qe) {
+		if len(qe.Param) == 0 {
+			qe.Param = param
+		}
+
+		return qe
+	}
+
+	return newQueryError(param, raw, 0, err)
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s: %s (%q at %d)", e.Param, e.Err, e.Token, e.Pos)
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// Adds the position of the error to the body of the response.
+func (e QueryError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		responseBody
+		Param string `json:"param"`
+		Token string `json:"token"`
+		Pos   int    `json:"pos"`
+	}{
+		responseBody: e.body(),
+		Param:        e.Param,
+		Token:        e.Token,
+		Pos:          e.Pos,
+	})
+}
@@ -0,0 +1,85 @@
+package restful_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/joernlenoch/go-restful"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestQueryError(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id"),
+			restful.Field("age").Int(),
+			restful.Field("password").Hidden(),
+		},
+		Table: "user",
+	}
+
+	for _, c := range []struct {
+		req      restful.Request
+		sentinel error
+		param    string
+		token    string
+		pos      int
+		message  string
+	}{
+		{restful.Request{Filter: "id=1,password=x"}, restful.ErrFilterNotAllowed, "filter", "password=x", 5, "filter-not-allowed"},
+		{restful.Request{Filter: "id=1 | age=abc"}, restful.ErrInvalidValue, "filter", "age=abc", 7, "invalid-value"},
+		{restful.Request{Filter: "id=1,(age>2"}, restful.ErrFilterStructure, "filter", "", 11, "invalid-filter"},
+		{restful.Request{Filter: "id=1,)"}, restful.ErrFilterStructure, "filter", ")", 5, "invalid-filter"},
+		{restful.Request{Filter: "id=1,ä ge=2"}, restful.ErrFilterStructure, "filter", "ä", 5, "invalid-filter"},
+		{restful.Request{Order: "id,-password"}, restful.ErrOrderNotAllowed, "order", "-password", 3, "order-not-allowed"},
+		{restful.Request{Order: "id,+-age"}, restful.ErrOrderInvalidStructure, "order", "+-age", 3, "invalid-order"},
+		{restful.Request{Cursor: "abc"}, restful.ErrCursorNotSupported, "cursor", "abc", 0, "cursor-not-supported"},
+	} {
+		_, _, err := restful.Prepare(cfg, c.req)
+		assert.True(t, errors.Is(err, c.sentinel), c.req)
+
+		var qe *restful.QueryError
+		if !assert.True(t, errors.As(err, &qe), c.req) {
+			continue
+		}
+
+		assert.Equal(t, c.param, qe.Param, c.req)
+		assert.Equal(t, c.token, qe.Token, c.req)
+		assert.Equal(t, c.pos, qe.Pos, c.req)
+		assert.Equal(t, c.message, qe.GetMessage(), c.req)
+		assert.Equal(t, http.StatusBadRequest, qe.GetCode(), c.req)
+	}
+}
+
+func TestQueryError_Response(t *testing.T) {
+	restful.Development = false
+
+	defer func() {
+		restful.Development = true
+	}()
+
+	cfg := restful.Config{Fields: restful.Fields{restful.Field("id")}, Table: "user"}
+
+	_, _, err := restful.Prepare(cfg, restful.Request{Filter: "name=x"})
+
+	// Stacking must not turn the error into a server error
+	resp := restful.Stack(err)
+	assert.Equal(t, http.StatusBadRequest, resp.GetCode())
+	assert.Equal(t, "filter-not-allowed", resp.GetMessage())
+	assert.True(t, errors.Is(resp, restful.ErrFilterNotAllowed))
+
+	out, err := json.Marshal(resp)
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, `{"message":"filter-not-allowed","reason":"the filter is not allowed","param":"filter","token":"name=x","pos":0}`, string(out))
+
+	// The stack and source are only added during development
+	restful.Development = true
+
+	out, err = json.Marshal(resp)
+	assert.NoError(t, err, "must not throw errors")
+	assert.Contains(t, string(out), `"stack":["`)
+	assert.Contains(t, string(out), `"source":"the filter is not allowed","param":"filter","token":"name=x","pos":0}`)
+}
@@ -19,14 +19,33 @@ func init() {
 		log.Fatal("Unable to compile regular expression: ", err)
 	}
 
-	filterRegex, err = regexp.Compile("^([a-zA-Z0-9_]+)(!=|~=|=|<|>|<=|>=|<>)([a-zA-ZäüöÄÜÖß0-9_:.-\\\\*]+)$")
+	// A relation with an optional field selection, e.g. "company(name,city)"
+	expandRegex, err = regexp.Compile("^([a-zA-Z0-9_]+)(?:\\(([a-zA-Z0-9_,]*)\\))?$")
+	if err != nil {
+		log.Fatal("Unable to compile regular expression: ", err)
+	}
+
+	aggregateRegex, err = regexp.Compile("(?i)\\b(COUNT|SUM|AVG|MIN|MAX|GROUP_CONCAT|STRING_AGG|ARRAY_AGG|JSON_AGG|JSONB_AGG|JSON_ARRAYAGG|JSON_OBJECTAGG|BIT_AND|BIT_OR|BIT_XOR|BOOL_AND|BOOL_OR|STDDEV|STDDEV_POP|STDDEV_SAMP|VARIANCE|VAR_POP|VAR_SAMP)\\s*\\(")
+	if err != nil {
+		log.Fatal("Unable to compile regular expression: ", err)
+	}
+
+	subQueryRegex, err = regexp.Compile("(?i)\\bSELECT\\b")
+	if err != nil {
+		log.Fatal("Unable to compile regular expression: ", err)
+	}
+
+	bareSubQueryRegex, err = regexp.Compile("(?i)^\\s*SELECT\\b")
 	if err != nil {
 		log.Fatal("Unable to compile regular expression: ", err)
 	}
 }
 
 var (
-	orderRegex  *regexp.Regexp
-	filterRegex *regexp.Regexp
-	fieldRegex  *regexp.Regexp
+	orderRegex        *regexp.Regexp
+	fieldRegex        *regexp.Regexp
+	expandRegex       *regexp.Regexp
+	aggregateRegex    *regexp.Regexp
+	subQueryRegex     *regexp.Regexp
+	bareSubQueryRegex *regexp.Regexp
 )
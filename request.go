@@ -0,0 +1,93 @@
+package restful
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ParseRequest reads the request parameters from the query string and, for POST and PUT
+// requests, the form body. Malformed input results in a Response with a 400 code.
+func ParseRequest(r *http.Request) (Request, error) {
+	if err := r.ParseForm(); err != nil {
+		return Request{}, BadRequestWithReason(MsgInvalidRequest, err.Error())
+	}
+
+	return RequestFromValues(r.Form)
+}
+
+// RequestFromValues reads the request parameters from the given values. Repeated lists are
+// combined, e.g. "filter=a=1&filter=b=2" equals "filter=a=1,b=2", while limit, offset and cursor
+// must be given once at most. Malformed input results in a Response with a 400 code.
+func RequestFromValues(values url.Values) (Request, error) {
+	req := Request{
+		Fields: joinValues(values["fields"], ","),
+		Filter: joinValues(values["filter"], ","),
+		Order:  joinValues(values["order"], ","),
+		Search: joinValues(values["search"], " "),
+		Expand: joinValues(values["expand"], ","),
+	}
+
+	var err error
+
+	if req.Cursor, err = singleValue(values, "cursor"); err != nil {
+		return Request{}, err
+	}
+
+	if req.Limit, err = uintValue(values, "limit"); err != nil {
+		return Request{}, err
+	}
+
+	if req.Offset, err = uintValue(values, "offset"); err != nil {
+		return Request{}, err
+	}
+
+	return req, nil
+}
+
+// Joins the non empty values with the separator.
+func joinValues(values []string, sep string) string {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		if len(v) > 0 {
+			parts = append(parts, v)
+		}
+	}
+
+	return strings.Join(parts, sep)
+}
+
+// Returns the value of a parameter that must not be repeated.
+func singleValue(values url.Values, name string) (string, error) {
+	v := values[name]
+
+	if len(v) > 1 {
+		return "", BadRequestWithReason(MsgInvalidRequest, name+" must be given once")
+	}
+
+	if len(v) == 0 {
+		return "", nil
+	}
+
+	return v[0], nil
+}
+
+// Returns the value of a non-negative integer parameter, 0 if missing.
+func uintValue(values url.Values, name string) (uint, error) {
+	v, err := singleValue(values, name)
+	if err != nil || len(v) == 0 {
+		return 0, err
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, BadRequestWithReason(MsgInvalidRequest, name+" must be an integer")
+	}
+
+	if n < 0 {
+		return 0, BadRequestWithReason(MsgInvalidRequest, name+" must not be negative")
+	}
+
+	return uint(n), nil
+}
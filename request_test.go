@@ -0,0 +1,62 @@
+package restful_test
+
+import (
+	"github.com/joernlenoch/go-restful"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseRequest(t *testing.T) {
+	t.Parallel()
+
+	r := httptest.NewRequest(http.MethodGet, "/users?fields=id,name&filter=age%3E3&filter=&filter=name%3Danna"+
+		"&order=-age&order=name&limit=10&offset=20&search=john&search=berlin&cursor=abc&expand=company", nil)
+
+	req, err := restful.ParseRequest(r)
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, restful.Request{
+		Fields: "id,name",
+		Filter: "age>3,name=anna",
+		Order:  "-age,name",
+		Limit:  10,
+		Offset: 20,
+		Search: "john berlin",
+		Cursor: "abc",
+		Expand: "company",
+	}, req)
+
+	// Form bodies are read as well
+	r = httptest.NewRequest(http.MethodPost, "/users?limit=5", strings.NewReader("filter=age%3E3"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	req, err = restful.ParseRequest(r)
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, restful.Request{Filter: "age>3", Limit: 5}, req)
+}
+
+func TestRequestFromValues_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for query, reason := range map[string]string{
+		"limit=abc":                   "limit must be an integer",
+		"limit=1.5":                   "limit must be an integer",
+		"offset=-1":                   "offset must not be negative",
+		"limit=1&limit=2":             "limit must be given once",
+		"cursor=a&cursor=b":           "cursor must be given once",
+		"offset=99999999999999999999": "offset must be an integer",
+	} {
+		values, _ := url.ParseQuery(query)
+
+		_, err := restful.RequestFromValues(values)
+		resp, ok := err.(restful.Response)
+
+		assert.True(t, ok, query)
+		assert.Equal(t, http.StatusBadRequest, resp.GetCode(), query)
+		assert.Equal(t, restful.MsgInvalidRequest, resp.GetMessage(), query)
+		assert.Equal(t, reason, resp.GetReason(), query)
+	}
+}
@@ -0,0 +1,80 @@
+package restful
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrFieldForbidden = errors.New("the field is not accessible")
+)
+
+type (
+	// Principal is the caller of a request, e.g. the authenticated user.
+	Principal interface {
+		HasScope(scope string) bool
+	}
+
+	// Scopes is a simple principal that is granted the listed scopes or roles.
+	Scopes []string
+
+	principalKey struct{}
+)
+
+func (s Scopes) HasScope(scope string) bool {
+	for _, v := range s {
+		if v == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WithPrincipal returns a context that carries the principal for PrepareContext and CountContext.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal of the context or nil.
+func PrincipalFrom(ctx context.Context) Principal {
+	p, _ := ctx.Value(principalKey{}).(Principal)
+	return p
+}
+
+// Restrict the field to callers with at least one of the given scopes or roles. Inaccessible fields
+// are not selected and cannot be used to filter, order or search.
+func (f field) RequireScopes(scopes ...string) field {
+	f.RequiredScopes = scopes
+	return f
+}
+
+func (f field) accessibleBy(p Principal) bool {
+	if len(f.RequiredScopes) == 0 {
+		return true
+	}
+
+	if p == nil {
+		return false
+	}
+
+	for _, s := range f.RequiredScopes {
+		if p.HasScope(s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the config with all fields marked, that the principal cannot access.
+func (cfg Config) restrict(p Principal) Config {
+	fields := make(Fields, len(cfg.Fields))
+	for i, f := range cfg.Fields {
+		f.forbidden = !f.accessibleBy(p)
+		fields[i] = f
+	}
+
+	cfg.Fields = fields
+	return cfg
+}
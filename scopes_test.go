@@ -0,0 +1,112 @@
+package restful_test
+
+import (
+	"context"
+	"errors"
+	"github.com/joernlenoch/go-restful"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPrepareContext_Scopes(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id"),
+			restful.Field("name").Searchable(),
+			restful.Field("salary").RequireScopes("hr", "admin").Searchable(),
+		},
+		Table: "user",
+	}
+
+	// Without a principal the scoped field does not exist for the caller
+	query, _, err := restful.Prepare(cfg, restful.Request{Search: "an"})
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT id, name FROM user WHERE name LIKE :__restful_search ESCAPE '!' LIMIT 50", query)
+
+	_, _, err = restful.Prepare(cfg, restful.Request{Fields: "id,salary"})
+	assert.True(t, errors.Is(err, restful.ErrFieldForbidden), "must not select the field")
+
+	_, _, err = restful.Prepare(cfg, restful.Request{Filter: "salary>100"})
+	assert.True(t, errors.Is(err, restful.ErrFieldForbidden), "must not filter the field")
+
+	_, _, err = restful.Prepare(cfg, restful.Request{Order: "-salary"})
+	assert.True(t, errors.Is(err, restful.ErrFieldForbidden), "must not order by the field")
+
+	_, _, err = restful.PrepareContext(restful.WithPrincipal(context.Background(), restful.Scopes{"user"}), cfg,
+		restful.Request{Filter: "salary>100"})
+	assert.True(t, errors.Is(err, restful.ErrFieldForbidden), "must require one of the scopes")
+
+	// Any of the scopes grants access
+	ctx := restful.WithPrincipal(context.Background(), restful.Scopes{"user", "hr"})
+
+	query, args, err := restful.PrepareContext(ctx, cfg, restful.Request{Filter: "salary>100", Order: "-salary"})
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT id, name, salary FROM user WHERE salary > :__restful_filter0 ORDER BY salary DESC LIMIT 50", query)
+	assert.Equal(t, "100", args["__restful_filter0"])
+
+	query, _, err = restful.CountContext(ctx, cfg, restful.Request{Filter: "salary>100"})
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT COUNT(*) FROM (SELECT id, name, salary FROM user WHERE salary > :__restful_filter0) t", query)
+
+	_, _, err = restful.PrepareCount(cfg, restful.Request{Filter: "salary>100"}, "*")
+	assert.True(t, errors.Is(err, restful.ErrFieldForbidden), "must not count by the field")
+
+	query, _, err = restful.PrepareCountContext(ctx, cfg, restful.Request{Filter: "salary>100"}, "*")
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT COUNT(*) FROM user WHERE salary > :__restful_filter0", query)
+}
+
+func TestPrepareContext_ScopedOrder(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id").Int(),
+			restful.Field("salary").Int().RequireScopes("admin").OrderBy(restful.DESC),
+		},
+		Table:     "user",
+		CursorKey: "id",
+	}
+
+	// The inaccessible field neither orders the rows nor ends up in the cursor
+	cursor, err := restful.NextCursor(cfg, restful.Request{}, map[string]interface{}{"id": 1, "salary": 99999})
+	assert.NoError(t, err, "must not throw errors")
+
+	query, args, err := restful.Prepare(cfg, restful.Request{Cursor: cursor})
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT id FROM user WHERE ((id > :__restful_cursor0)) ORDER BY id ASC LIMIT 50", query)
+	assert.Equal(t, map[string]interface{}{"__restful_cursor0": int64(1)}, args)
+
+	ctx := restful.WithPrincipal(context.Background(), restful.Scopes{"admin"})
+
+	cursor, err = restful.NextCursorContext(ctx, cfg, restful.Request{}, map[string]interface{}{"id": 1, "salary": 99999})
+	assert.NoError(t, err, "must not throw errors")
+
+	query, _, err = restful.PrepareContext(ctx, cfg, restful.Request{Cursor: cursor})
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT id, salary FROM user WHERE ((salary < :__restful_cursor0) OR "+
+		"(salary = :__restful_cursor0 AND id > :__restful_cursor1)) ORDER BY salary DESC, id ASC LIMIT 50", query)
+
+	// A cursor of another principal does not match the order
+	_, _, err = restful.Prepare(cfg, restful.Request{Cursor: cursor})
+	assert.True(t, errors.Is(err, restful.ErrCursorInvalid))
+
+	cfg.CursorKey = "salary"
+
+	_, err = restful.PrevCursor(cfg, restful.Request{}, map[string]interface{}{"id": 1, "salary": 99999})
+	assert.True(t, errors.Is(err, restful.ErrFieldForbidden), "must not expose the key")
+
+	_, err = restful.PrevCursorContext(ctx, cfg, restful.Request{}, map[string]interface{}{"id": 1, "salary": 99999})
+	assert.NoError(t, err, "must not throw errors")
+}
+
+func TestScopes_HasScope(t *testing.T) {
+	t.Parallel()
+
+	s := restful.Scopes{"read", "write"}
+	assert.True(t, s.HasScope("write"))
+	assert.False(t, s.HasScope("admin"))
+	assert.Nil(t, restful.PrincipalFrom(context.Background()))
+}
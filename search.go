@@ -0,0 +1,342 @@
+package restful
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Defines how Request.Search is matched.
+type SearchMode int
+
+const (
+	// Matches the searchable fields with LIKE, the default.
+	SearchLike SearchMode = iota
+
+	// MySQL MATCH ... AGAINST in natural language mode over the FULLTEXT columns.
+	SearchNatural SearchMode = iota
+
+	// MySQL MATCH ... AGAINST in boolean mode, the search may use the boolean operators.
+	SearchBoolean SearchMode = iota
+
+	// PostgreSQL to_tsvector(...) @@ plainto_tsquery(...).
+	SearchTSVector SearchMode = iota
+)
+
+// Defines how a searchable field matches a term.
+type MatchMode int
+
+const (
+	MatchContains MatchMode = iota
+	MatchPrefix   MatchMode = iota
+	MatchExact    MatchMode = iota
+)
+
+const searchKey = "__restful_search"
+
+// The escape character of all LIKE patterns. Unlike the backslash, it needs no escaping within
+// string literals of any dialect.
+const likeEscape = '!'
+
+type searchTerm struct {
+	value   string
+	exclude bool
+}
+
+// Match the search terms against the field with the given mode, defaults to MatchContains.
+func (f field) Match(mode MatchMode) field {
+	f.MatchMode = mode
+	return f
+}
+
+// Returns the LIKE pattern of the term for the mode.
+func (m MatchMode) pattern(term string) string {
+	term = likePattern(term)
+
+	switch m {
+	case MatchPrefix:
+		return term + "%"
+	case MatchExact:
+		return term
+	}
+
+	return "%" + term + "%"
+}
+
+// Returns the suffix of the argument key for the mode. The modes need different patterns of
+// the same term.
+func (m MatchMode) suffix() string {
+	switch m {
+	case MatchPrefix:
+		return "_prefix"
+	case MatchExact:
+		return "_exact"
+	}
+
+	return ""
+}
+
+// Tells if the search is handled by a full-text index.
+func (m SearchMode) fullText() bool {
+	return m != SearchLike
+}
+
+// Returns the columns matched by a full-text search. Unless declared, the searchable fields
+// are used. Declared columns of inaccessible fields are dropped.
+func (cfg Config) searchColumns() []string {
+	if len(cfg.SearchColumns) > 0 {
+		columns := make([]string, 0, len(cfg.SearchColumns))
+
+	columnsLoop:
+		for _, c := range cfg.SearchColumns {
+			for _, f := range cfg.Fields {
+				if f.forbidden && (c == f.Name || c == f.filterExpr()) {
+					continue columnsLoop
+				}
+			}
+
+			columns = append(columns, c)
+		}
+
+		return columns
+	}
+
+	columns := make([]string, 0, len(cfg.Fields))
+	for _, f := range cfg.Fields {
+		if f.IsSearchable && !f.forbidden {
+			columns = append(columns, f.filterExpr())
+		}
+	}
+
+	return columns
+}
+
+// Returns the tsvector of the search columns.
+func (cfg Config) tsVector(columns []string) string {
+	doc := columns[0]
+	if len(columns) > 1 {
+		doc = fmt.Sprintf("concat_ws(' ', %s)", strings.Join(columns, ", "))
+	}
+
+	if len(cfg.SearchLanguage) > 0 {
+		return fmt.Sprintf("to_tsvector('%s', %s)", strings.Replace(cfg.SearchLanguage, "'", "''", -1), doc)
+	}
+
+	return fmt.Sprintf("to_tsvector(%s)", doc)
+}
+
+// Returns the tsquery of the search.
+func (cfg Config) tsQuery() string {
+	if len(cfg.SearchLanguage) > 0 {
+		return fmt.Sprintf("plainto_tsquery('%s', :%s)", strings.Replace(cfg.SearchLanguage, "'", "''", -1), searchKey)
+	}
+
+	return fmt.Sprintf("plainto_tsquery(:%s)", searchKey)
+}
+
+// Returns the full-text match expression. For MySQL, it also is the relevance of the row.
+func (cfg Config) match(columns []string) string {
+	switch cfg.SearchMode {
+	case SearchTSVector:
+		return fmt.Sprintf("%s @@ %s", cfg.tsVector(columns), cfg.tsQuery())
+	case SearchBoolean:
+		return fmt.Sprintf("MATCH (%s) AGAINST (:%s IN BOOLEAN MODE)", strings.Join(columns, ", "), searchKey)
+	}
+
+	return fmt.Sprintf("MATCH (%s) AGAINST (:%s IN NATURAL LANGUAGE MODE)", strings.Join(columns, ", "), searchKey)
+}
+
+// Returns the config with the relevance pseudo-field added, if the request is searched by a
+// full-text index.
+func (cfg Config) withRelevance(search string) Config {
+	if len(cfg.Relevance) == 0 || len(search) == 0 || !cfg.SearchMode.fullText() {
+		return cfg
+	}
+
+	columns := cfg.searchColumns()
+	if len(columns) == 0 {
+		return cfg
+	}
+
+	rank := cfg.match(columns)
+	if cfg.SearchMode == SearchTSVector {
+		rank = fmt.Sprintf("ts_rank(%s, %s)", cfg.tsVector(columns), cfg.tsQuery())
+	}
+
+	fields := make(Fields, len(cfg.Fields), len(cfg.Fields)+1)
+	copy(fields, cfg.Fields)
+
+	cfg.Fields = append(fields, Field(cfg.Relevance).QueryBy(rank).Sortable())
+	return cfg
+}
+
+// Creates the search condition and adds the search argument.
+func prepareSearch(cfg Config, args *map[string]interface{}, req string) (string, error) {
+
+	if len(req) == 0 {
+		return "", nil
+	}
+
+	if cfg.SearchMode.fullText() {
+		columns := cfg.searchColumns()
+		if len(columns) == 0 {
+			return "", nil
+		}
+
+		(*args)[searchKey] = req
+		return cfg.match(columns), nil
+	}
+
+	terms := splitSearch(req)
+
+	// Limit the terms, as each one adds a condition per searchable field
+	maxTerms := cfg.MaxSearchTerms
+	if maxTerms <= 0 {
+		maxTerms = SearchTermsDefault
+	}
+
+	if len(terms) > maxTerms {
+		return "", ErrSearchTooManyTerms
+	}
+
+	// Every term matches every searchable field
+	conditions := make([]string, 0, len(terms))
+
+	for i, t := range terms {
+		cond := prepareSearchTerm(cfg, args, t, i)
+		if len(cond) > 0 {
+			conditions = append(conditions, cond)
+		}
+	}
+
+	return strings.Join(conditions, " AND "), nil
+}
+
+// Creates the condition that at least one searchable field matches the term, or none for
+// exclusions. The first term is bound to the search key, the following ones are numbered.
+func prepareSearchTerm(cfg Config, args *map[string]interface{}, t searchTerm, index int) string {
+	key := searchKey
+	if index > 0 {
+		key = fmt.Sprintf("%s%d", searchKey, index)
+	}
+
+	parts := make([]string, 0, len(cfg.Fields))
+
+	for _, f := range cfg.Fields {
+		if !f.IsSearchable || f.forbidden {
+			continue
+		}
+
+		k := key + f.MatchMode.suffix()
+		(*args)[k] = f.MatchMode.pattern(t.value)
+
+		expr := f.filterExpr()
+
+		// NULL would turn the whole exclusion into NULL and hide the row
+		if t.exclude && f.IsNullable {
+			expr = fmt.Sprintf("COALESCE(%s, '')", expr)
+		}
+
+		parts = append(parts, like(cfg.dialect(), expr, k))
+	}
+
+	if len(parts) == 0 {
+		return ""
+	}
+
+	out := strings.Join(parts, " OR ")
+
+	if t.exclude {
+		return fmt.Sprintf("NOT (%s)", out)
+	}
+
+	if len(parts) == 1 {
+		return out
+	}
+
+	return fmt.Sprintf("(%s)", out)
+}
+
+// Splits the search into its terms. Terms are separated by whitespace, "quoted phrases" are
+// kept together and a leading "-" excludes the term.
+func splitSearch(raw string) []searchTerm {
+	terms := make([]searchTerm, 0, 1)
+	runes := []rune(raw)
+
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		t := searchTerm{}
+		if runes[i] == '-' {
+			i++
+
+			// A dangling "-" excludes nothing
+			if i == len(runes) || unicode.IsSpace(runes[i]) {
+				continue
+			}
+
+			t.exclude = true
+		}
+
+		start := i
+		if runes[i] == '"' {
+			start++
+			end := start
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+
+			t.value = string(runes[start:end])
+			i = end + 1
+		} else {
+			for i < len(runes) && !unicode.IsSpace(runes[i]) {
+				i++
+			}
+
+			t.value = string(runes[start:i])
+		}
+
+		if len(strings.TrimSpace(t.value)) > 0 {
+			terms = append(terms, t)
+		}
+	}
+
+	return terms
+}
+
+// Converts the user value into a LIKE pattern. "*" is the wildcard, "\*" matches a literal
+// asterisk and "\\" a literal backslash. Everything else, including "%", "_" and the "[" of a
+// SQL Server character class, is matched literally.
+func likePattern(value string) string {
+	out := strings.Builder{}
+	runes := []rune(value)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch r {
+		case '\\':
+			if i+1 < len(runes) && (runes[i+1] == '*' || runes[i+1] == '\\') {
+				i++
+				r = runes[i]
+			}
+		case '*':
+			out.WriteRune('%')
+			continue
+		case '%', '_', '[', likeEscape:
+			out.WriteRune(likeEscape)
+		}
+
+		out.WriteRune(r)
+	}
+
+	return out.String()
+}
+
+// Returns the LIKE condition of the expression and the argument key.
+func like(d Dialect, expr, key string) string {
+	return fmt.Sprintf("%s %s :%s ESCAPE '%c'", expr, d.Like(), key, likeEscape)
+}
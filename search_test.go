@@ -0,0 +1,179 @@
+package restful_test
+
+import (
+	"context"
+	"errors"
+	"github.com/joernlenoch/go-restful"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPrepare_SearchNatural(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id"),
+			restful.Field("title").Searchable(),
+			restful.Field("body").Searchable(),
+		},
+		Table:      "post",
+		SearchMode: restful.SearchNatural,
+		Relevance:  "relevance",
+	}
+
+	query, args, err := restful.Prepare(cfg, restful.Request{Search: "go database", Order: "-relevance"})
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT id, title, body, MATCH (title, body) AGAINST (:__restful_search IN NATURAL LANGUAGE MODE) AS 'relevance' "+
+		"FROM post WHERE MATCH (title, body) AGAINST (:__restful_search IN NATURAL LANGUAGE MODE) "+
+		"ORDER BY MATCH (title, body) AGAINST (:__restful_search IN NATURAL LANGUAGE MODE) DESC LIMIT 50", query)
+	assert.Equal(t, "go database", args["__restful_search"])
+
+	// The relevance only exists while searching
+	_, _, err = restful.Prepare(cfg, restful.Request{Order: "-relevance"})
+	assert.True(t, errors.Is(err, restful.ErrOrderNotAllowed))
+}
+
+func TestPrepare_SearchBoolean(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id"),
+			restful.Field("title"),
+		},
+		Table:         "post",
+		SearchMode:    restful.SearchBoolean,
+		SearchColumns: []string{"title", "body"},
+	}
+
+	query, args, err := restful.Prepare(cfg, restful.Request{Search: "+go -java"})
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT id, title FROM post WHERE MATCH (title, body) AGAINST (:__restful_search IN BOOLEAN MODE) LIMIT 50", query)
+	assert.Equal(t, "+go -java", args["__restful_search"])
+
+	// Columns of inaccessible fields are never searched
+	cfg.Fields = append(cfg.Fields, restful.Field("notes").QueryBy("p.notes").RequireScopes("editor"))
+	cfg.SearchColumns = []string{"title", "p.notes"}
+
+	query, _, err = restful.Prepare(cfg, restful.Request{Fields: "id", Search: "go"})
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT id FROM post WHERE MATCH (title) AGAINST (:__restful_search IN BOOLEAN MODE) LIMIT 50", query)
+
+	ctx := restful.WithPrincipal(context.Background(), restful.Scopes{"editor"})
+
+	query, _, err = restful.PrepareContext(ctx, cfg, restful.Request{Fields: "id", Search: "go"})
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT id FROM post WHERE MATCH (title, p.notes) AGAINST (:__restful_search IN BOOLEAN MODE) LIMIT 50", query)
+}
+
+func TestPrepare_SearchTSVector(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id"),
+			restful.Field("title").Searchable(),
+			restful.Field("body").Searchable(),
+		},
+		Table:          "post",
+		Dialect:        restful.PostgreSQL,
+		SearchMode:     restful.SearchTSVector,
+		SearchLanguage: "english",
+		Relevance:      "rank",
+	}
+
+	query, _, err := restful.Prepare(cfg, restful.Request{Fields: "id", Search: "databases", Order: "-rank"})
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT id FROM post "+
+		"WHERE to_tsvector('english', concat_ws(' ', title, body)) @@ plainto_tsquery('english', :__restful_search) "+
+		"ORDER BY ts_rank(to_tsvector('english', concat_ws(' ', title, body)), plainto_tsquery('english', :__restful_search)) DESC LIMIT 50", query)
+
+	query, _, err = restful.PrepareCount(cfg, restful.Request{Search: "databases"}, "*")
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT COUNT(*) FROM post "+
+		"WHERE to_tsvector('english', concat_ws(' ', title, body)) @@ plainto_tsquery('english', :__restful_search)", query)
+}
+
+func TestPrepare_SearchTerms(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id"),
+			restful.Field("name").Searchable(),
+			restful.Field("city").Searchable().Nullable(),
+			restful.Field("code").Searchable().Match(restful.MatchPrefix),
+		},
+		Table: "user",
+	}
+
+	query, args, err := restful.Prepare(cfg, restful.Request{Fields: "id", Search: `john  "new york" -ber*`})
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT id FROM user WHERE "+
+		"(name LIKE :__restful_search ESCAPE '!' OR city LIKE :__restful_search ESCAPE '!' OR code LIKE :__restful_search_prefix ESCAPE '!') AND "+
+		"(name LIKE :__restful_search1 ESCAPE '!' OR city LIKE :__restful_search1 ESCAPE '!' OR code LIKE :__restful_search1_prefix ESCAPE '!') AND "+
+		"NOT (name LIKE :__restful_search2 ESCAPE '!' OR COALESCE(city, '') LIKE :__restful_search2 ESCAPE '!' OR code LIKE :__restful_search2_prefix ESCAPE '!') LIMIT 50", query)
+
+	assert.Equal(t, "%john%", args["__restful_search"])
+	assert.Equal(t, "john%", args["__restful_search_prefix"])
+	assert.Equal(t, "%new york%", args["__restful_search1"])
+	assert.Equal(t, "%ber%%", args["__restful_search2"])
+
+	cfg.MaxSearchTerms = 2
+
+	_, _, err = restful.Prepare(cfg, restful.Request{Search: `john  "new york" -ber*`})
+	assert.True(t, errors.Is(err, restful.ErrSearchTooManyTerms))
+
+	_, _, err = restful.Count(cfg, restful.Request{Search: `john "new york"`})
+	assert.NoError(t, err, "must not throw errors")
+}
+
+func TestPrepare_SearchExact(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id"),
+			restful.Field("email").Searchable().Match(restful.MatchExact),
+		},
+		Table: "user",
+	}
+
+	query, args, err := restful.Prepare(cfg, restful.Request{Search: ` "anna@example.com" `})
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT id, email FROM user WHERE email LIKE :__restful_search_exact ESCAPE '!' LIMIT 50", query)
+	assert.Equal(t, "anna@example.com", args["__restful_search_exact"])
+
+	query, _, err = restful.Prepare(cfg, restful.Request{Search: `"" - `})
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, "SELECT id, email FROM user LIMIT 50", query, "must ignore empty terms")
+}
+
+func TestPrepare_SearchEscaping(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("name").Searchable(),
+		},
+		Table:   "user",
+		Dialect: restful.PostgreSQL,
+	}
+
+	for search, expected := range map[string]string{
+		"50%":       "%50!%%",
+		"user_name": "%user!_name%",
+		"wow!":      "%wow!!%",
+		"[a-c]":     "%![a-c]%",
+		"a*b":       "%a%b%",
+		`a\*b`:      "%a*b%",
+		`a\\b`:      `%a\b%`,
+		`a\b`:       `%a\b%`,
+	} {
+		query, args, err := restful.Prepare(cfg, restful.Request{Search: search})
+		assert.NoError(t, err, "must not throw errors")
+		assert.Equal(t, `SELECT name FROM user WHERE name ILIKE :__restful_search ESCAPE '!' LIMIT 50`, query)
+		assert.Equal(t, expected, args["__restful_search"], search)
+	}
+}
@@ -0,0 +1,160 @@
+package restful
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidValue = errors.New("the filter value is invalid")
+
+	decimalRegex = regexp.MustCompile(`^[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)$`)
+	uuidRegex    = regexp.MustCompile(`^[0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12}$`)
+)
+
+// The type of a field, used to convert filter values before they are passed to the database.
+type FieldType int
+
+const (
+	TypeString  FieldType = iota
+	TypeInt     FieldType = iota
+	TypeBool    FieldType = iota
+	TypeTime    FieldType = iota
+	TypeUUID    FieldType = iota
+	TypeDecimal FieldType = iota
+	TypeEnum    FieldType = iota
+)
+
+// ValueError is returned when a filter value does not match the type of its field.
+type ValueError struct {
+	Field string
+	Value string
+	Type  FieldType
+}
+
+func (e *ValueError) Error() string {
+	return fmt.Sprintf("invalid value %q for the %s field %s", e.Value, e.Type, e.Field)
+}
+
+func (e *ValueError) Unwrap() error {
+	return ErrInvalidValue
+}
+
+// Values are passed to the database as int64
+func (f field) Int() field {
+	f.Type = TypeInt
+	return f
+}
+
+// Values are passed as bool, accepts 1, t, true, 0, f, false, ...
+func (f field) Bool() field {
+	f.Type = TypeBool
+	return f
+}
+
+// Values are parsed with the given layout and passed as time.Time, defaults to RFC 3339
+func (f field) Time(layout string) field {
+	f.Type = TypeTime
+	f.Layout = layout
+	return f
+}
+
+// Values are passed as lower case UUID string with dashes
+func (f field) UUID() field {
+	f.Type = TypeUUID
+	return f
+}
+
+// Values are validated but passed as string to not lose any precision
+func (f field) Decimal() field {
+	f.Type = TypeDecimal
+	return f
+}
+
+// Only the given values are accepted
+func (f field) Enum(values ...string) field {
+	f.Type = TypeEnum
+	f.Values = values
+	return f
+}
+
+// Converts a filter value into the type of the field.
+func (f field) parse(value string) (interface{}, error) {
+
+	fail := &ValueError{Field: f.Name, Value: value, Type: f.Type}
+
+	switch f.Type {
+	case TypeInt:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fail
+		}
+		return v, nil
+
+	case TypeBool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fail
+		}
+		return v, nil
+
+	case TypeTime:
+		layout := f.Layout
+		if len(layout) == 0 {
+			layout = time.RFC3339
+		}
+
+		v, err := time.Parse(layout, value)
+		if err != nil {
+			return nil, fail
+		}
+		return v, nil
+
+	case TypeUUID:
+		if !uuidRegex.MatchString(value) {
+			return nil, fail
+		}
+
+		v := strings.ToLower(strings.Replace(value, "-", "", -1))
+		return fmt.Sprintf("%s-%s-%s-%s-%s", v[0:8], v[8:12], v[12:16], v[16:20], v[20:32]), nil
+
+	case TypeDecimal:
+		if !decimalRegex.MatchString(value) {
+			return nil, fail
+		}
+		return value, nil
+
+	case TypeEnum:
+		for _, v := range f.Values {
+			if v == value {
+				return value, nil
+			}
+		}
+		return nil, fail
+	}
+
+	return value, nil
+}
+
+func (t FieldType) String() string {
+	switch t {
+	case TypeInt:
+		return "int"
+	case TypeBool:
+		return "bool"
+	case TypeTime:
+		return "time"
+	case TypeUUID:
+		return "uuid"
+	case TypeDecimal:
+		return "decimal"
+	case TypeEnum:
+		return "enum"
+	}
+
+	return "string"
+}
@@ -0,0 +1,79 @@
+package restful_test
+
+import (
+	"errors"
+	"github.com/joernlenoch/go-restful"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestTypes_Filter(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id").UUID(),
+			restful.Field("age").Int(),
+			restful.Field("active").Bool(),
+			restful.Field("born").Time("2006-01-02"),
+			restful.Field("balance").Decimal(),
+			restful.Field("status").Enum("open", "closed"),
+		},
+		Table: "user",
+	}
+
+	_, args, err := restful.Prepare(cfg, restful.Request{
+		Fields: "id",
+		Filter: "id=0B0A7E44F8AB4A0D9C3B2B1C7A1D8E5F,age=in:(18|21),active=true,born=between:2000-01-01..,balance>=-10.50,status=open",
+	})
+
+	assert.NoError(t, err, "must not throw errors")
+	assert.Equal(t, map[string]interface{}{
+		"__restful_filter0":      "0b0a7e44-f8ab-4a0d-9c3b-2b1c7a1d8e5f",
+		"__restful_filter1_0":    int64(18),
+		"__restful_filter1_1":    int64(21),
+		"__restful_filter2":      true,
+		"__restful_filter3_from": time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		"__restful_filter4":      "-10.50",
+		"__restful_filter5":      "open",
+	}, args)
+}
+
+func TestTypes_Invalid(t *testing.T) {
+	t.Parallel()
+
+	cfg := restful.Config{
+		Fields: restful.Fields{
+			restful.Field("id").UUID(),
+			restful.Field("age").Int(),
+			restful.Field("active").Bool(),
+			restful.Field("born").Time("2006-01-02"),
+			restful.Field("balance").Decimal(),
+			restful.Field("status").Enum("open", "closed"),
+		},
+		Table: "user",
+	}
+
+	for filter, value := range map[string]string{
+		"age=abc":                   "abc",
+		"age=in:(1|x)":              "x",
+		"active=yes":                "yes",
+		"born=between:..2000-13-01": "2000-13-01",
+		"balance=1.2.3":             "1.2.3",
+		"status=pending":            "pending",
+		"id=1234":                   "1234",
+	} {
+		_, _, err := restful.Prepare(cfg, restful.Request{Filter: filter})
+
+		var valueErr *restful.ValueError
+		if assert.True(t, errors.As(err, &valueErr), filter) {
+			assert.Equal(t, value, valueErr.Value, filter)
+		}
+
+		assert.True(t, errors.Is(err, restful.ErrInvalidValue), filter)
+	}
+
+	_, _, err := restful.Count(cfg, restful.Request{Filter: "age=abc"})
+	assert.EqualError(t, err, `filter: invalid value "abc" for the int field age ("age=abc" at 0)`)
+}